@@ -13,6 +13,7 @@ require (
 
 require (
 	github.com/lekuruu/ubisoft-game-service/proxy v0.0.0-00010101000000-000000000000
+	golang.org/x/crypto v0.26.0 // indirect
 	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948 // indirect
 	golang.org/x/time v0.0.0-20220722155302-e5dcc9cfc0b9 // indirect
 	gopkg.in/irc.v4 v4.0.0 // indirect
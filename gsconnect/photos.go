@@ -0,0 +1,41 @@
+package gsconnect
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Same as the username pattern of the router, as photos are stored by account name
+var photoNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_\-.]*$`)
+
+// PhotoSource provides the photo of an account, e.g. the router's PhotoStore
+type PhotoSource interface {
+	Load(name string) ([]byte, error)
+}
+
+// Serve player photos uploaded through the router, e.g. "/photos/<username>"
+func PhotoRoute(gsc *GSContext) {
+	if gsc.Request.Method != http.MethodGet && gsc.Request.Method != http.MethodHead {
+		gsc.Response.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.ToLower(strings.TrimPrefix(gsc.Request.URL.Path, "/photos/"))
+
+	if !photoNamePattern.MatchString(name) {
+		gsc.Response.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	data, err := gsc.Server.Photos.Load(name)
+
+	if err != nil {
+		gsc.Response.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	gsc.Response.Header().Set("Content-Type", http.DetectContentType(data))
+	gsc.Response.WriteHeader(http.StatusOK)
+	gsc.Response.Write(data)
+}
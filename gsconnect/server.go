@@ -12,6 +12,7 @@ type GSConnect struct {
 	Port   int
 	Logger common.Logger
 	Games  map[string]string
+	Photos PhotoSource
 }
 
 type GSContext struct {
@@ -25,6 +26,11 @@ func (gsc *GSConnect) Serve() {
 	gsc.Logger.Info(fmt.Sprintf("Listening on %s", bind))
 
 	http.HandleFunc("/gsinit.php", gsc.withGSContext(GSInitRoute))
+
+	if gsc.Photos != nil {
+		http.HandleFunc("/photos/", gsc.withGSContext(PhotoRoute))
+	}
+
 	http.ListenAndServe(bind, nil)
 }
 
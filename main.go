@@ -3,9 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 
 	"github.com/lekuruu/ubisoft-game-service/cdkey"
 	"github.com/lekuruu/ubisoft-game-service/common"
@@ -22,8 +26,9 @@ type Config struct {
 		Port int
 	}
 	Router struct {
-		Host string
-		Port int
+		Host                 string
+		Port                 int
+		DisableHostMigration bool
 	}
 	Proxy struct {
 		Host string
@@ -39,8 +44,13 @@ type Config struct {
 	CDKey struct {
 		Port int
 	}
-	Games        []string
-	ExternalHost string
+	Accounts struct {
+		ForbiddenWords string
+		ReservedNames  string
+	}
+	Games         []string
+	ExternalHost  string
+	DataDirectory string
 }
 
 func (c *Config) createGameConfig() map[string]string {
@@ -75,6 +85,7 @@ func loadConfig() (*Config, error) {
 
 	flag.StringVar(&config.Router.Host, "router-host", "0.0.0.0", "Router server host")
 	flag.IntVar(&config.Router.Port, "router-port", 40000, "Router server port")
+	flag.BoolVar(&config.Router.DisableHostMigration, "disable-host-migration", false, "Close rooms when their master leaves")
 
 	flag.StringVar(&config.Proxy.Host, "proxy-host", "0.0.0.0", "Proxy server host")
 	flag.IntVar(&config.Proxy.Port, "proxy-port", 4040, "Proxy server port")
@@ -86,6 +97,9 @@ func loadConfig() (*Config, error) {
 	flag.IntVar(&config.CDKey.Port, "cdkey-port", 44000, "CDKey server port")
 
 	flag.StringVar(&config.ExternalHost, "external-host", "127.0.0.1", "External host address")
+	flag.StringVar(&config.Accounts.ForbiddenWords, "forbidden-words", "", "Comma-separated words that are not allowed in usernames")
+	flag.StringVar(&config.Accounts.ReservedNames, "reserved-names", "", "Comma-separated usernames that can't be registered")
+	flag.StringVar(&config.DataDirectory, "data-dir", "./data", "Directory for persistent data")
 	flag.Parse()
 
 	// Default games list
@@ -127,18 +141,93 @@ func main() {
 		return
 	}
 
+	accounts, err := router.NewFileAccountStore(config.DataDirectory)
+
+	if err != nil {
+		fmt.Println("Failed to load account store:", err)
+		return
+	}
+
+	photoDirectory := filepath.Join(config.DataDirectory, "photos")
+	photos, err := router.NewPhotoStore(photoDirectory)
+
+	if err != nil {
+		fmt.Println("Failed to load photo store:", err)
+		return
+	}
+
+	motd, err := router.NewConfigFile(
+		filepath.Join(config.DataDirectory, "motd.json"),
+		router.DefaultMotdConfig(),
+	)
+
+	if err != nil {
+		fmt.Println("Failed to load motd config:", err)
+		return
+	}
+
+	news, err := router.NewConfigFile(
+		filepath.Join(config.DataDirectory, "news.json"),
+		[]router.NewsItem{},
+	)
+
+	if err != nil {
+		fmt.Println("Failed to load news config:", err)
+		return
+	}
+
+	advertisements, err := router.NewConfigFile(
+		filepath.Join(config.DataDirectory, "advertisements.json"),
+		[]router.Advertisement{},
+	)
+
+	if err != nil {
+		fmt.Println("Failed to load advertisements config:", err)
+		return
+	}
+
+	versions, err := router.NewConfigFile(
+		filepath.Join(config.DataDirectory, "versions.json"),
+		router.VersionConfig{},
+	)
+
+	if err != nil {
+		fmt.Println("Failed to load versions config:", err)
+		return
+	}
+
 	gsc := gsconnect.GSConnect{
 		Host:   config.Web.Host,
 		Port:   config.Web.Port,
 		Games:  config.createGameConfig(),
 		Logger: *common.CreateLogger("GSConnect", common.DEBUG),
+		Photos: photos,
+	}
+
+	policy := router.DefaultAccountPolicy()
+
+	if config.Accounts.ForbiddenWords != "" {
+		policy.ForbiddenWords = strings.Split(config.Accounts.ForbiddenWords, ",")
+	}
+
+	if config.Accounts.ReservedNames != "" {
+		policy.ReservedNames = strings.Split(config.Accounts.ReservedNames, ",")
 	}
 
 	router := router.Router{
-		Host:   config.Router.Host,
-		Port:   uint16(config.Router.Port),
-		Logger: *common.CreateLogger("Router", common.DEBUG),
-		Games:  config.Games,
+		Host:           config.Router.Host,
+		Port:           uint16(config.Router.Port),
+		Logger:         *common.CreateLogger("Router", common.DEBUG),
+		Games:          config.Games,
+		Accounts:       accounts,
+		Photos:         photos,
+		Motd:           motd,
+		News:           news,
+		Advertisements: advertisements,
+		Versions:       versions,
+		Policy:         policy,
+
+		DisableHostMigration: config.Router.DisableHostMigration,
 	}
 
 	proxy := proxy.Proxy{
@@ -172,5 +261,17 @@ func main() {
 	runService(&wg, nat.Serve)
 	runService(&wg, gsc.Serve)
 
+	// Accept admin commands, e.g. "ban <name> 24h cheating"
+	go router.RunConsole(os.Stdin)
+
+	// Write buffered account changes before exiting
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		router.FlushAccounts()
+		os.Exit(0)
+	}()
+
 	wg.Wait()
 }
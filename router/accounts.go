@@ -0,0 +1,200 @@
+package router
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrAccountNotFound    = errors.New("account not found")
+	ErrAccountExists      = errors.New("account already exists")
+	ErrPasswordNotCorrect = errors.New("password not correct")
+)
+
+const (
+	ACCOUNT_ACTIVE  = 0
+	ACCOUNT_BANNED  = 1
+	ACCOUNT_BLOCKED = 2
+	ACCOUNT_LOCKED  = 3
+)
+
+type AccountStatus struct {
+	State  int
+	Reason string
+	Expiry time.Time // Zero value means no expiry
+}
+
+// A page that was sent while the receiver was offline
+type Page struct {
+	Sender  string
+	Message string
+	SentAt  time.Time
+}
+
+type Account struct {
+	Id        int
+	Name      string
+	Password  string
+	Info      Info
+	Status    AccountStatus
+	Friends   []string
+	Ignored   []string
+	Pages     []Page
+	CreatedAt time.Time
+}
+
+// AccountStore is the persistence layer used by the router to look up
+// and manage registered accounts. Implementations must be safe for
+// concurrent use and should return copies, not shared references.
+// Create assigns a unique and stable id to the account.
+type AccountStore interface {
+	Get(name string) (*Account, error)
+	Create(account *Account) error
+	Delete(name string) error
+
+	// Atomically apply changes to an account, which is
+	// discarded if the update function returns an error
+	Modify(name string, update func(account *Account) error) error
+
+	// Return the accounts matching the query, sorted by name
+	Search(query AccountQuery) ([]*Account, error)
+}
+
+// Stores that buffer their writes implement this,
+// so that pending changes can be written regularly
+type FlushableStore interface {
+	Flush() error
+}
+
+type AccountQuery struct {
+	Pattern string // Name prefix, or a pattern with '*' and '?' wildcards
+	Country string // Optional
+	Offset  int
+	Limit   int // Zero means no limit
+}
+
+func (query *AccountQuery) Matches(account *Account) bool {
+	if query.Country != "" && !strings.EqualFold(query.Country, account.Info.Country) {
+		return false
+	}
+
+	name := strings.ToLower(account.Name)
+	pattern := strings.ToLower(query.Pattern)
+
+	if !strings.ContainsAny(pattern, "*?") {
+		return strings.HasPrefix(name, pattern)
+	}
+
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+func (account *Account) clone() *Account {
+	copied := *account
+	copied.Friends = append([]string(nil), account.Friends...)
+	copied.Ignored = append([]string(nil), account.Ignored...)
+	copied.Pages = append([]Page(nil), account.Pages...)
+	return &copied
+}
+
+func (account *Account) HasFriend(name string) bool {
+	return containsName(account.Friends, name)
+}
+
+func (account *Account) IsIgnoring(name string) bool {
+	return containsName(account.Ignored, name)
+}
+
+func (account *Account) hasPagesFrom(sender string) bool {
+	for _, page := range account.Pages {
+		if strings.EqualFold(page.Sender, sender) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (account *Account) removePagesFrom(sender string) {
+	pages := make([]Page, 0, len(account.Pages))
+
+	for _, page := range account.Pages {
+		if !strings.EqualFold(page.Sender, sender) {
+			pages = append(pages, page)
+		}
+	}
+
+	account.Pages = pages
+}
+
+// Set the account password to the bcrypt hash of the given plaintext
+func (account *Account) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	account.Password = string(hash)
+	return nil
+}
+
+// Check if the given plaintext password matches the stored hash
+func (account *Account) CheckPassword(password string) bool {
+	err := bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(password))
+	return err == nil
+}
+
+// Check if the account is currently restricted, and return the
+// matching ERRORSECURE_* code, or zero if the account may log in
+func (account *Account) RestrictionCode() int {
+	if account.Status.State == ACCOUNT_ACTIVE {
+		return 0
+	}
+
+	if !account.Status.Expiry.IsZero() && time.Now().After(account.Status.Expiry) {
+		// Restriction has expired
+		return 0
+	}
+
+	switch account.Status.State {
+	case ACCOUNT_BANNED:
+		return ERRORSECURE_BANNEDACCOUNT
+	case ACCOUNT_BLOCKED:
+		return ERRORSECURE_BLOCKEDACCOUNT
+	case ACCOUNT_LOCKED:
+		return ERRORSECURE_LOCKEDACCOUNT
+	default:
+		return ERRORSECURE_INVALIDACCOUNT
+	}
+}
+
+// Account names are unique regardless of their casing
+func accountKey(name string) string {
+	return strings.ToLower(name)
+}
+
+func containsName(names []string, name string) bool {
+	for _, entry := range names {
+		if strings.EqualFold(entry, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeName(names []string, name string) []string {
+	result := make([]string, 0, len(names))
+
+	for _, entry := range names {
+		if !strings.EqualFold(entry, name) {
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var accountStates = map[string]int{
+	"ban":   ACCOUNT_BANNED,
+	"block": ACCOUNT_BLOCKED,
+	"lock":  ACCOUNT_LOCKED,
+	"unban": ACCOUNT_ACTIVE,
+}
+
+// Change the status of an account, and disconnect any live
+// session of the account, if it is restricted from now on
+func (router *Router) SetAccountStatus(name string, status AccountStatus) error {
+	err := router.Accounts.Modify(name, func(account *Account) error {
+		account.Status = status
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if status.State == ACCOUNT_ACTIVE {
+		return nil
+	}
+
+	if player := router.Players.ByName(name); player != nil && player.Client != nil {
+		router.Logger.Info(fmt.Sprintf("Disconnecting restricted player '%s'", player.Name))
+		player.Disconnect()
+	}
+
+	return nil
+}
+
+// Execute a single admin command, e.g. "ban <name> [duration] [reason]"
+// or "unban <name>", and return a message describing the result
+func (router *Router) HandleCommand(line string) (string, error) {
+	fields := strings.Fields(line)
+
+	if len(fields) < 2 {
+		return "", errors.New("usage: ban|block|lock <name> [duration] [reason], unban <name>")
+	}
+
+	state, ok := accountStates[strings.ToLower(fields[0])]
+	if !ok {
+		return "", fmt.Errorf("unknown command '%s'", fields[0])
+	}
+
+	status := AccountStatus{State: state}
+	arguments := fields[2:]
+
+	if state != ACCOUNT_ACTIVE && len(arguments) > 0 {
+		if duration, err := time.ParseDuration(arguments[0]); err == nil {
+			status.Expiry = time.Now().Add(duration)
+			arguments = arguments[1:]
+		}
+
+		status.Reason = strings.Join(arguments, " ")
+	}
+
+	if err := router.SetAccountStatus(fields[1], status); err != nil {
+		return "", err
+	}
+
+	router.FlushAccounts()
+
+	if status.Expiry.IsZero() {
+		return fmt.Sprintf("Applied '%s' to '%s'", fields[0], fields[1]), nil
+	}
+
+	return fmt.Sprintf("Applied '%s' to '%s' until %s", fields[0], fields[1], status.Expiry.Format(time.RFC3339)), nil
+}
+
+// Read admin commands line by line, until the reader is closed
+func (router *Router) RunConsole(reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		result, err := router.HandleCommand(scanner.Text())
+
+		if err != nil {
+			router.Logger.Warning(fmt.Sprintf("Command failed: %s", err))
+			continue
+		}
+
+		router.Logger.Info(result)
+	}
+}
@@ -0,0 +1,99 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+type Advertisement struct {
+	Id    int
+	Game  string // Empty for all games
+	Image string // Image reference or URL of the banner
+	Link  string
+	Start time.Time // Zero value means no start date
+	End   time.Time // Zero value means no end date
+}
+
+func (ad *Advertisement) IsActive(now time.Time) bool {
+	if !ad.Start.IsZero() && now.Before(ad.Start) {
+		return false
+	}
+
+	return ad.End.IsZero() || now.Before(ad.End)
+}
+
+func (ad *Advertisement) Entry() []interface{} {
+	return []interface{}{strconv.Itoa(ad.Id), ad.Image, ad.Link}
+}
+
+// Keeps track of the active advertisements, so that players
+// only get notified when the banner rotation changes
+type AdvertisementNotifier struct {
+	mutex  sync.Mutex
+	active string
+}
+
+// Report whether the set of active advertisements changed since the last call
+func (notifier *AdvertisementNotifier) Changed(ads []Advertisement) bool {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	now := time.Now()
+	ids := []string{}
+
+	for _, ad := range ads {
+		if ad.IsActive(now) {
+			ids = append(ids, fmt.Sprintf("%d:%s:%s:%s", ad.Id, ad.Game, ad.Image, ad.Link))
+		}
+	}
+
+	sort.Strings(ids)
+	active := strings.Join(ids, ",")
+
+	if active == notifier.active {
+		return false
+	}
+
+	notifier.active = active
+	return true
+}
+
+func (router *Router) advertisementsFor(game string) []interface{} {
+	now := time.Now()
+	entries := []interface{}{}
+
+	for _, ad := range router.Advertisements.Get() {
+		if ad.IsActive(now) && (ad.Game == "" || ad.Game == game) {
+			entries = append(entries, ad.Entry())
+		}
+	}
+
+	return entries
+}
+
+// Push the current banners to all online players, if they have changed
+func (router *Router) announceAdvertisements() {
+	if !router.AdvertisementNotifier.Changed(router.Advertisements.Get()) {
+		return
+	}
+
+	for _, player := range router.Players.All() {
+		entries := router.advertisementsFor(player.CurrentGame())
+		player.Send(NewPushMessage(GSM_UPDATEADVERTISMEMENTS, []interface{}{entries}))
+	}
+}
+
+func handleAdvertisementRequest(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	entries := client.Server.advertisementsFor(client.Player.CurrentGame())
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_ADVERTISEMENT), entries}
+	return response, nil
+}
@@ -0,0 +1,119 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+const CLIENT_QUEUE_SIZE = 128
+const CLIENT_WRITE_TIMEOUT = 10 * time.Second
+
+var (
+	ErrClientClosed  = errors.New("client connection was closed")
+	ErrSendQueueFull = errors.New("client send queue is full")
+)
+
+type Client struct {
+	Conn   net.Conn
+	Server *Router
+	Player *Player
+	State  *common.GSClientState
+
+	queue  chan *common.GSMessage
+	done   chan struct{}
+	closed bool
+	mutex  sync.Mutex
+}
+
+func NewClient(conn net.Conn, server *Router) *Client {
+	return &Client{
+		Conn:   conn,
+		Server: server,
+		State:  &common.GSClientState{},
+		queue:  make(chan *common.GSMessage, CLIENT_QUEUE_SIZE),
+		done:   make(chan struct{}),
+	}
+}
+
+// Queue a message to be sent to the client. This is safe to call from any
+// goroutine, and will never block: If the client can't keep up with the
+// messages it receives, the connection will be closed instead.
+func (client *Client) Send(msg *common.GSMessage) error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.closed || client.queue == nil {
+		return ErrClientClosed
+	}
+
+	select {
+	case client.queue <- msg:
+		return nil
+	default:
+		client.Conn.Close()
+		return ErrSendQueueFull
+	}
+}
+
+// Close the connection after all queued messages have been sent
+func (client *Client) Disconnect() {
+	client.closeQueue()
+	client.Conn.Close()
+}
+
+// Create a message that is sent to the client without a prior request
+func NewPushMessage(msgType uint8, data []interface{}) *common.GSMessage {
+	return &common.GSMessage{
+		Property: common.GSM_PROPERTY_GS,
+		Type:     msgType,
+		Sender:   TARGET_W,
+		Receiver: TARGET_P,
+		Data:     data,
+	}
+}
+
+// Write queued messages to the connection, until the queue gets closed
+func (client *Client) writeLoop() {
+	defer close(client.done)
+
+	for msg := range client.queue {
+		serialized, err := msg.Serialize(client.State)
+
+		if err != nil {
+			client.Server.Logger.Error(fmt.Sprintf("Failed to serialize message: %s", err))
+			continue
+		}
+
+		client.Conn.SetWriteDeadline(time.Now().Add(CLIENT_WRITE_TIMEOUT))
+		_, err = client.Conn.Write(serialized)
+
+		if err != nil {
+			client.Server.Logger.Error(fmt.Sprintf("Failed to send message: %s", err))
+			client.Conn.Close()
+			continue
+		}
+
+		client.Server.Logger.Debug(fmt.Sprintf("<- %v", msg.String()))
+	}
+}
+
+// Stop accepting new messages, and wait for the writer to finish the remaining ones
+func (client *Client) closeQueue() {
+	client.mutex.Lock()
+
+	if client.closed || client.queue == nil {
+		client.mutex.Unlock()
+		return
+	}
+
+	client.closed = true
+	close(client.queue)
+	client.mutex.Unlock()
+
+	<-client.done
+}
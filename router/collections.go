@@ -1,25 +1,59 @@
 package router
 
+import "sync"
+
+// PlayerCollection is safe for concurrent use. Lookups return the
+// stored *Player pointers, while All() and ByGame() return a snapshot
+// slice, that will not change when players are added or removed.
 type PlayerCollection struct {
+	mutex   sync.RWMutex
 	idMap   map[int]*Player
 	nameMap map[string]*Player
 }
 
-func (collection *PlayerCollection) Add(player *Player) {
+// Add the player to the collection, unless a player
+// with the same name is already part of it
+func (collection *PlayerCollection) Add(player *Player) bool {
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	key := accountKey(player.Name)
+
+	if _, ok := collection.nameMap[key]; ok {
+		return false
+	}
+
 	collection.idMap[player.Id] = player
-	collection.nameMap[player.Name] = player
+	collection.nameMap[key] = player
+	return true
 }
 
 func (collection *PlayerCollection) Remove(player *Player) {
-	delete(collection.idMap, player.Id)
-	delete(collection.nameMap, player.Name)
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	// Only remove the entries that belong to this exact player
+	if collection.idMap[player.Id] == player {
+		delete(collection.idMap, player.Id)
+	}
+
+	key := accountKey(player.Name)
+
+	if collection.nameMap[key] == player {
+		delete(collection.nameMap, key)
+	}
 }
 
 func (collection *PlayerCollection) Count() int {
-	return len(collection.idMap)
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+	return len(collection.nameMap)
 }
 
 func (collection *PlayerCollection) ByID(id int) *Player {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
 	if val, ok := collection.idMap[id]; ok {
 		return val
 	}
@@ -28,37 +62,46 @@ func (collection *PlayerCollection) ByID(id int) *Player {
 }
 
 func (collection *PlayerCollection) ByName(name string) *Player {
-	if val, ok := collection.nameMap[name]; ok {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	if val, ok := collection.nameMap[accountKey(name)]; ok {
 		return val
 	}
 
 	return nil
 }
 
-func (collection *PlayerCollection) All() []Player {
-	players := make([]Player, 0, len(collection.idMap))
+func (collection *PlayerCollection) All() []*Player {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	players := make([]*Player, 0, len(collection.nameMap))
 
-	for _, player := range collection.idMap {
-		players = append(players, *player)
+	for _, player := range collection.nameMap {
+		players = append(players, player)
 	}
 
 	return players
 }
 
-func (collection *PlayerCollection) ByGame(game string) []Player {
-	players := make([]Player, 0)
+func (collection *PlayerCollection) ByGame(game string) []*Player {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	players := make([]*Player, 0)
 
-	for _, player := range collection.idMap {
-		if player.Game == game {
-			players = append(players, *player)
+	for _, player := range collection.nameMap {
+		if player.CurrentGame() == game {
+			players = append(players, player)
 		}
 	}
 
 	return players
 }
 
-func NewPlayerCollection() PlayerCollection {
-	return PlayerCollection{
+func NewPlayerCollection() *PlayerCollection {
+	return &PlayerCollection{
 		idMap:   make(map[int]*Player),
 		nameMap: make(map[string]*Player),
 	}
@@ -0,0 +1,171 @@
+package router
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+const testPlayerCount = 50
+
+func newTestRouter(t *testing.T) *Router {
+	accounts, err := NewFileAccountStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Router{
+		Logger:   *common.CreateLogger("Router", common.ERROR),
+		Accounts: accounts,
+		Players:  NewPlayerCollection(),
+		Groups:   NewGroupCollection(),
+		Pending:  NewPendingLogins(PENDING_LOGIN_TIMEOUT),
+	}
+}
+
+// Create a client, whose messages are written to an in-memory connection
+func newTestClient(router *Router) *Client {
+	server, peer := net.Pipe()
+	go io.Copy(io.Discard, peer)
+
+	client := NewClient(server, router)
+	go client.writeLoop()
+	return client
+}
+
+func TestPlayerCollectionConcurrentAccess(t *testing.T) {
+	collection := NewPlayerCollection()
+	var wg sync.WaitGroup
+
+	for i := 0; i < testPlayerCount; i++ {
+		wg.Add(2)
+
+		go func(id int) {
+			defer wg.Done()
+
+			player := &Player{Id: id, Name: fmt.Sprintf("Player%d", id)}
+
+			for j := 0; j < 100; j++ {
+				collection.Add(player)
+				collection.ByName(player.Name)
+				collection.ByID(player.Id)
+				collection.Remove(player)
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				for _, player := range collection.All() {
+					collection.ByName(player.Name)
+				}
+				collection.ByGame("HEROES_5")
+				collection.Count()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if count := collection.Count(); count != 0 {
+		t.Fatalf("expected an empty collection, got %d players", count)
+	}
+}
+
+func TestPlayerCollectionRejectsDuplicateNames(t *testing.T) {
+	collection := NewPlayerCollection()
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	added := 0
+
+	for i := 0; i < testPlayerCount; i++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			if collection.Add(&Player{Id: id, Name: "Player"}) {
+				mutex.Lock()
+				added++
+				mutex.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if added != 1 {
+		t.Fatalf("expected exactly one player to be added, got %d", added)
+	}
+}
+
+func TestConcurrentLoginAndDisconnect(t *testing.T) {
+	router := newTestRouter(t)
+
+	for i := 0; i < testPlayerCount; i++ {
+		account := &Account{Name: fmt.Sprintf("Player%d", i)}
+
+		if err := router.Accounts.Create(account); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < testPlayerCount; i++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			name := fmt.Sprintf("Player%d", id)
+			client := newTestClient(router)
+
+			// All clients share the same address, so the token is the only way to tell them apart
+			player := &Player{Id: id + 1, Name: name}
+			token := router.Pending.Add(player, client.IpAddress())
+
+			request := &common.GSMessage{Data: []interface{}{name, token}}
+			_, gsError := handleWaitModuleLogin(request, client)
+
+			if gsError != nil {
+				t.Errorf("login of '%s' failed: %s", name, gsError)
+			}
+
+			if client.Player != player {
+				t.Errorf("'%s' was logged in as the wrong player", name)
+			}
+
+			client.Send(NewPushMessage(GSM_GSSUCCESS, []interface{}{}))
+			router.OnDisconnect(client)
+		}(i)
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			deadline := time.Now().Add(50 * time.Millisecond)
+
+			for time.Now().Before(deadline) {
+				for _, player := range router.Players.All() {
+					player.Send(NewPushMessage(GSM_GSSUCCESS, []interface{}{}))
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if count := router.Players.Count(); count != 0 {
+		t.Fatalf("expected all players to be disconnected, got %d", count)
+	}
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+const CONFIG_POLL_INTERVAL = 5 * time.Second
+
+// ConfigFile holds a value that is loaded from a JSON file, and
+// reloaded whenever the file gets modified while the server is running.
+// If the file doesn't exist yet, it is created with the default value.
+type ConfigFile[T any] struct {
+	path       string
+	mutex      sync.Mutex
+	modTime    time.Time
+	generation int
+	value      T
+}
+
+func NewConfigFile[T any](path string, defaultValue T) (*ConfigFile[T], error) {
+	config := &ConfigFile[T]{path: path, value: defaultValue}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		data, err := json.MarshalIndent(defaultValue, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := config.Reload(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Get the current value, reloading it first if the file has changed.
+// If reloading fails, the previous value is kept.
+func (config *ConfigFile[T]) Get() T {
+	config.Reload()
+
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+	return config.value
+}
+
+// Reload the file if it has been modified, and report whether it changed
+func (config *ConfigFile[T]) Reload() (bool, error) {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	info, err := os.Stat(config.path)
+	if err != nil {
+		return false, err
+	}
+
+	if info.ModTime().Equal(config.modTime) {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(config.path)
+	if err != nil {
+		return false, err
+	}
+
+	var value T
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return false, err
+	}
+
+	config.value = value
+	config.modTime = info.ModTime()
+	config.generation++
+	return true, nil
+}
+
+// Get a number that is increased every time the file was reloaded
+func (config *ConfigFile[T]) Generation() int {
+	config.Reload()
+
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+	return config.generation
+}
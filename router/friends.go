@@ -0,0 +1,277 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+// Get the status & mood of a player, as seen by other players
+func (router *Router) visibleStatus(name string) (uint32, uint32) {
+	status, mood := uint32(STATUS_PLAYEROFFLINE), uint32(0)
+
+	if player := router.Players.ByName(name); player != nil && player.FriendsLoggedIn() {
+		status, mood = player.FriendStatus()
+	}
+
+	if status == STATUS_PLAYERINVISIBLE {
+		// Invisible players appear offline to everyone else
+		status, mood = STATUS_PLAYEROFFLINE, 0
+	}
+
+	return status, mood
+}
+
+// Create the friend list entry for the given account name
+func (router *Router) friendEntry(name string) []interface{} {
+	status, mood := router.visibleStatus(name)
+	return []interface{}{name, common.WriteU32(status), common.WriteU32(mood)}
+}
+
+// Send the current status of a player to all online players,
+// that have them on their friend list
+func (router *Router) notifyFriends(name string) {
+	entry := router.friendEntry(name)
+
+	for _, player := range router.Players.All() {
+		if strings.EqualFold(player.Name, name) || !player.FriendsLoggedIn() {
+			continue
+		}
+
+		account, err := router.Accounts.Get(player.Name)
+		if err != nil || !account.HasFriend(name) {
+			continue
+		}
+
+		err = player.Send(NewPushMessage(GSM_UPDATEFRIEND, entry))
+
+		if err != nil {
+			router.Logger.Warning(fmt.Sprintf("Failed to send friend update to '%s': %s", player.Name, err))
+		}
+	}
+}
+
+// Returns an error, if the target player is ignoring the sender
+func (router *Router) checkIgnored(target string, sender string) GSError {
+	account, err := router.Accounts.Get(target)
+	if err != nil {
+		// Missing accounts are handled by the caller
+		return nil
+	}
+
+	if account.IsIgnoring(sender) {
+		return &RouterError{
+			Message:      fmt.Sprintf("'%s' is ignoring '%s'", account.Name, sender),
+			ResponseCode: ERRORFRIENDS_PLAYERIGNORE,
+		}
+	}
+
+	return nil
+}
+
+func handleAddFriend(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	friendName, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	if strings.EqualFold(friendName, client.Player.Name) {
+		return nil, &RouterError{
+			Message:      "player can't add themselves as a friend",
+			ResponseCode: ERRORFRIENDS_FRIENDNOTEXIST,
+		}
+	}
+
+	// Make sure that the account is not removed, while it's being referenced
+	client.Server.accountRemoval.RLock()
+	defer client.Server.accountRemoval.RUnlock()
+
+	friend, err := client.Server.Accounts.Get(friendName)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      "friend account not found",
+			ResponseCode: ERRORFRIENDS_FRIENDNOTEXIST,
+		}
+	}
+
+	err = client.Server.Accounts.Modify(client.Player.Name, func(account *Account) error {
+		if !account.HasFriend(friend.Name) {
+			account.Friends = append(account.Friends, friend.Name)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{
+		common.WriteU8(GSM_ADDFRIEND),
+		client.Server.friendEntry(friend.Name),
+	}
+	return response, nil
+}
+
+func handleDeleteFriend(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	friendName, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	err = client.Server.Accounts.Modify(client.Player.Name, func(account *Account) error {
+		if !account.HasFriend(friendName) {
+			return ErrAccountNotFound
+		}
+		account.Friends = removeName(account.Friends, friendName)
+		return nil
+	})
+
+	if err == ErrAccountNotFound {
+		return nil, &RouterError{
+			Message:      "player is not on the friend list",
+			ResponseCode: ERRORFRIENDS_FRIENDNOTEXIST,
+		}
+	}
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_DELFRIEND), friendName}
+	return response, nil
+}
+
+func handleFriendListRequest(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	account, err := client.Server.Accounts.Get(client.Player.Name)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	friends := []interface{}{}
+
+	for _, name := range account.Friends {
+		friends = append(friends, client.Server.friendEntry(name))
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_FRIENDLIST), friends}
+	return response, nil
+}
+
+func handleStatusChange(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	status, err := common.GetU32ListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	mood, err := common.GetU32ListItem(message.Data, 1)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	if status >= STATUS_PLAYERSTATUSCOUNT && (status < STATUS_PLAYERCORESTART || status > STATUS_PLAYERCOREEND) {
+		return nil, &RouterError{Message: fmt.Sprintf("invalid player status '%d'", status)}
+	}
+
+	client.Player.SetFriendStatus(status, mood)
+	client.Server.notifyFriends(client.Player.Name)
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_STATUSCHANGE)}
+	return response, nil
+}
+
+func handleAddIgnore(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	targetName, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	if strings.EqualFold(targetName, client.Player.Name) {
+		return nil, &RouterError{
+			Message:      "player can't ignore themselves",
+			ResponseCode: ERRORFRIENDS_FRIENDNOTEXIST,
+		}
+	}
+
+	// Make sure that the account is not removed, while it's being referenced
+	client.Server.accountRemoval.RLock()
+	defer client.Server.accountRemoval.RUnlock()
+
+	target, err := client.Server.Accounts.Get(targetName)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      "ignored account not found",
+			ResponseCode: ERRORFRIENDS_FRIENDNOTEXIST,
+		}
+	}
+
+	err = client.Server.Accounts.Modify(client.Player.Name, func(account *Account) error {
+		if !account.IsIgnoring(target.Name) {
+			account.Ignored = append(account.Ignored, target.Name)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_ADDIGNOREFRIEND), target.Name}
+	return response, nil
+}
+
+func handleDeleteIgnore(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	targetName, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	err = client.Server.Accounts.Modify(client.Player.Name, func(account *Account) error {
+		if !account.IsIgnoring(targetName) {
+			return ErrAccountNotFound
+		}
+		account.Ignored = removeName(account.Ignored, targetName)
+		return nil
+	})
+
+	if err == ErrAccountNotFound {
+		return nil, &RouterError{
+			Message:      "player is not on the ignore list",
+			ResponseCode: ERRORFRIENDS_FRIENDNOTEXIST,
+		}
+	}
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_DELIGNOREFRIEND), targetName}
+	return response, nil
+}
@@ -2,11 +2,14 @@ module github.com/lekuruu/ubisoft-game-service/router
 
 go 1.22.6
 
-require github.com/lekuruu/ubisoft-game-service/common v0.0.0-20240831105814-85a1b7e9b455
+require (
+	github.com/lekuruu/ubisoft-game-service/common v0.0.0-20240831105814-85a1b7e9b455
+	golang.org/x/crypto v0.26.0
+	golang.org/x/time v0.0.0-20220722155302-e5dcc9cfc0b9
+)
 
 require (
 	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948 // indirect
-	golang.org/x/time v0.0.0-20220722155302-e5dcc9cfc0b9 // indirect
 	gopkg.in/irc.v4 v4.0.0 // indirect
 )
 
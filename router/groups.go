@@ -0,0 +1,395 @@
+package router
+
+import (
+	"crypto/subtle"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+const (
+	GROUP_TYPE_LOBBY = 1
+	GROUP_TYPE_ROOM  = 2
+)
+
+const LOBBY_MAX_MEMBERS = 1000
+
+const (
+	ROOM_MAX_NAME_LENGTH = 32
+	ROOM_MAX_PLAYERS     = 64
+	ROOM_MAX_SPECTATORS  = 64
+)
+
+// A group is either a per-game lobby, or a room inside of a lobby
+type Group struct {
+	Id         int
+	ParentId   int // Zero for lobbies
+	Type       int
+	Name       string
+	Game       string
+	MaxMembers int
+	Members    *PlayerCollection
+
+	// Room settings chosen by the creator
+	GameType      string
+	Version       string
+	MaxSpectators int
+	Password      string
+	Creator       string
+
+	// Guards membership changes, so that checks and updates happen at once
+	mutex  sync.Mutex
+	closed bool
+	master *Player
+
+	// Members in the order they joined, used to pick the next master
+	joined []*Player
+
+	// Names of banned players, which can't join the group anymore
+	banned map[string]string
+}
+
+// Add a player to the group, and return an ERRORLOBBYSRV_* code on failure
+func (group *Group) AddMember(player *Player) int {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.closed {
+		return ERRORLOBBYSRV_GROUPNOTEXIST
+	}
+
+	if group.Members.ByName(player.Name) != nil {
+		return ERRORLOBBYSRV_ALREADYINGROUP
+	}
+
+	if _, ok := group.banned[accountKey(player.Name)]; ok {
+		return ERRORLOBBYSRV_MEMBERBANNED
+	}
+
+	if group.MaxMembers > 0 && group.Members.Count() >= group.MaxMembers {
+		if group.IsRoom() {
+			return ERRORLOBBYSRV_NOMOREPLAYERS
+		}
+		return ERRORLOBBYSRV_NOMOREMEMBERS
+	}
+
+	group.Members.Add(player)
+	group.joined = append(group.joined, player)
+
+	if group.IsRoom() && group.master == nil {
+		// The first member of a room is its creator
+		group.master = player
+	}
+
+	return 0
+}
+
+// Remove a player from the group, and report whether the player
+// was a member, as well as whether the player was the master
+func (group *Group) RemoveMember(player *Player) (bool, bool) {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.Members.ByName(player.Name) != player {
+		return false, false
+	}
+
+	group.Members.Remove(player)
+
+	for i, member := range group.joined {
+		if member == player {
+			group.joined = append(group.joined[:i], group.joined[i+1:]...)
+			break
+		}
+	}
+
+	if group.master != player {
+		return true, false
+	}
+
+	group.master = nil
+	return true, true
+}
+
+// Make the longest standing member the new master, if the group has none.
+// Returns nil if there is nobody left to take over.
+func (group *Group) ElectMaster() *Player {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.closed || group.master != nil || len(group.joined) == 0 {
+		return nil
+	}
+
+	group.master = group.joined[0]
+	return group.master
+}
+
+func (group *Group) Master() *Player {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	return group.master
+}
+
+func (group *Group) IsMaster(player *Player) bool {
+	return group.Master() == player
+}
+
+// Close the group and remove all of its members, which are returned
+func (group *Group) Close() []*Player {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.closed {
+		return nil
+	}
+
+	members := group.joined
+	group.closed = true
+	group.master = nil
+	group.joined = nil
+
+	for _, member := range members {
+		group.Members.Remove(member)
+	}
+
+	return members
+}
+
+// Close the group if it has no members left, and report whether it was closed
+func (group *Group) CloseIfEmpty() bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.closed || group.Members.Count() > 0 {
+		return false
+	}
+
+	group.closed = true
+	return true
+}
+
+// Ban a player by name, and report whether the player wasn't banned before
+func (group *Group) Ban(name string) bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.banned == nil {
+		group.banned = make(map[string]string)
+	}
+
+	if _, ok := group.banned[accountKey(name)]; ok {
+		return false
+	}
+
+	group.banned[accountKey(name)] = name
+	return true
+}
+
+// Lift the ban of a player, and report whether the player was banned
+func (group *Group) Unban(name string) bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if _, ok := group.banned[accountKey(name)]; !ok {
+		return false
+	}
+
+	delete(group.banned, accountKey(name))
+	return true
+}
+
+// Get the names of all banned players, sorted alphabetically
+func (group *Group) BanList() []string {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	names := make([]string, 0, len(group.banned))
+
+	for _, name := range group.banned {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func (group *Group) HasPassword() bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	return group.Password != ""
+}
+
+func (group *Group) CheckPassword(password string) bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	return subtle.ConstantTimeCompare([]byte(group.Password), []byte(password)) == 1
+}
+
+// Change the room settings, unless the room has more members than allowed
+func (group *Group) Configure(maxPlayers int, maxSpectators int, password string) bool {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	if group.Members.Count() > maxPlayers {
+		return false
+	}
+
+	group.MaxMembers = maxPlayers
+	group.MaxSpectators = maxSpectators
+	group.Password = password
+	return true
+}
+
+// Create the room browser entry for this group
+func (group *Group) Entry() []interface{} {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	hasPassword := 0
+	if group.Password != "" {
+		hasPassword = 1
+	}
+
+	return []interface{}{
+		strconv.Itoa(group.Id),
+		strconv.Itoa(group.ParentId),
+		group.Name,
+		group.GameType,
+		group.Version,
+		group.Creator,
+		strconv.Itoa(group.Members.Count()),
+		strconv.Itoa(group.MaxMembers),
+		strconv.Itoa(group.MaxSpectators),
+		common.WriteU8(hasPassword),
+	}
+}
+
+func (group *Group) IsLobby() bool {
+	return group.Type == GROUP_TYPE_LOBBY
+}
+
+func (group *Group) IsRoom() bool {
+	return group.Type == GROUP_TYPE_ROOM
+}
+
+// GroupCollection is safe for concurrent use, and assigns
+// a unique id to every group that gets added to it
+type GroupCollection struct {
+	mutex  sync.RWMutex
+	groups map[int]*Group
+	nextId int
+}
+
+func NewGroupCollection() *GroupCollection {
+	return &GroupCollection{
+		groups: make(map[int]*Group),
+		nextId: 1,
+	}
+}
+
+func (collection *GroupCollection) Add(group *Group) {
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	group.Id = collection.nextId
+	collection.groups[group.Id] = group
+	collection.nextId++
+}
+
+// Add a room to the collection, unless its parent
+// already contains a room with the same name
+func (collection *GroupCollection) AddRoom(room *Group) bool {
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	for _, group := range collection.groups {
+		if group.ParentId == room.ParentId && strings.EqualFold(group.Name, room.Name) {
+			return false
+		}
+	}
+
+	room.Id = collection.nextId
+	collection.groups[room.Id] = room
+	collection.nextId++
+	return true
+}
+
+func (collection *GroupCollection) Remove(group *Group) {
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+	delete(collection.groups, group.Id)
+}
+
+func (collection *GroupCollection) ByID(id int) *Group {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	if group, ok := collection.groups[id]; ok {
+		return group
+	}
+
+	return nil
+}
+
+func (collection *GroupCollection) LobbyByGame(game string) *Group {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	for _, group := range collection.groups {
+		if group.IsLobby() && group.Game == game {
+			return group
+		}
+	}
+
+	return nil
+}
+
+func (collection *GroupCollection) All() []*Group {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	groups := make([]*Group, 0, len(collection.groups))
+
+	for _, group := range collection.groups {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// Get all groups with the given parent, sorted by id
+func (collection *GroupCollection) Children(parentId int) []*Group {
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	groups := make([]*Group, 0)
+
+	for _, group := range collection.groups {
+		if group.ParentId == parentId {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Id < groups[j].Id
+	})
+
+	return groups
+}
+
+// Create one lobby for each of the supported games
+func (collection *GroupCollection) CreateLobbies(games []string) {
+	for _, game := range games {
+		collection.Add(&Group{
+			Type:       GROUP_TYPE_LOBBY,
+			Name:       game,
+			Game:       game,
+			MaxMembers: LOBBY_MAX_MEMBERS,
+			Members:    NewPlayerCollection(),
+		})
+	}
+}
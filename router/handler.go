@@ -16,6 +16,24 @@ import (
 var RouterHandlers = map[uint8]func(*common.GSMessage, *Client) (*common.GSMessage, GSError){}
 var LobbyHandlers = map[int]func(*common.GSMessage, *Client) (*common.GSMessage, GSError){}
 
+// Message types that can be sent before the player has logged in
+var PublicHandlers = map[uint8]bool{
+	GSM_STILLALIVE:      true,
+	GSM_KEY_EXCHANGE:    true,
+	GSM_NEWUSERREQUEST:  true,
+	GSM_LOGIN:           true,
+	GSM_JOINWAITMODULE:  true,
+	GSM_LOGINWAITMODULE: true,
+	GSM_MOTD_REQUEST:    true,
+}
+
+func requireLogin(message *common.GSMessage, _ *Client) (*common.GSMessage, GSError) {
+	return nil, &RouterError{
+		Message:      fmt.Sprintf("message type '%d' requires a login", message.Type),
+		ResponseCode: ERRORROUTER_PLAYERNOTCONNECTED,
+	}
+}
+
 func stillAlive(message *common.GSMessage, _ *Client) (*common.GSMessage, GSError) {
 	return common.NewGSMessageFromRequest(message), nil
 }
@@ -101,6 +119,88 @@ func handleKeyExchange(message *common.GSMessage, client *Client) (*common.GSMes
 	return response, nil
 }
 
+func handleNewUserRequest(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	username, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	password, err := common.GetStringListItem(message.Data, 1)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	// The remaining profile fields are optional
+	firstname, _ := common.GetStringListItem(message.Data, 2)
+	surname, _ := common.GetStringListItem(message.Data, 3)
+	email, _ := common.GetStringListItem(message.Data, 4)
+	country, _ := common.GetStringListItem(message.Data, 5)
+
+	if code := client.Server.Policy.ValidateUsername(username); code != 0 {
+		return nil, &RouterError{
+			Message:      "invalid username",
+			ResponseCode: code,
+		}
+	}
+
+	if code := client.Server.Policy.ValidatePassword(username, password); code != 0 {
+		return nil, &RouterError{
+			Message:      "invalid password",
+			ResponseCode: code,
+		}
+	}
+
+	info := Info{
+		Firstname: firstname,
+		Surname:   surname,
+		Email:     email,
+		Country:   strings.ToUpper(country),
+	}
+
+	if code := client.Server.Policy.ValidateInfo(info); code != 0 {
+		return nil, &RouterError{
+			Message:      "invalid account info",
+			ResponseCode: code,
+		}
+	}
+
+	account := &Account{
+		Name:      username,
+		CreatedAt: time.Now(),
+		Info:      info,
+	}
+
+	if err := account.SetPassword(password); err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_INVALIDPASSWORD,
+		}
+	}
+
+	err = client.Server.Accounts.Create(account)
+
+	if err == ErrAccountExists {
+		return nil, &RouterError{
+			Message:      "account already exists",
+			ResponseCode: ERRORSECURE_USERNAMEEXISTS,
+		}
+	}
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_DATABASEFAILED,
+		}
+	}
+
+	client.Server.Logger.Info(fmt.Sprintf("Registered new account '%s'", username))
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_NEWUSERREQUEST)}
+	return response, nil
+}
+
 func handleLogin(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
 	username, err := common.GetStringListItem(message.Data, 0)
 	if err != nil {
@@ -117,10 +217,51 @@ func handleLogin(message *common.GSMessage, client *Client) (*common.GSMessage,
 		return nil, &RouterError{Message: err.Error()}
 	}
 
-	// TODO: Implement login validation
-	// password, err := common.GetStringListItem(message.Data, 1)
+	password, err := common.GetStringListItem(message.Data, 1)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	account, err := client.Server.Accounts.Get(username)
+
+	if err == ErrAccountNotFound {
+		return nil, &RouterError{
+			Message:      "account not registered",
+			ResponseCode: ERRORROUTER_NOTREGISTERED,
+		}
+	}
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	if !account.CheckPassword(password) {
+		return nil, &RouterError{
+			Message:      "password not correct",
+			ResponseCode: ERRORROUTER_PASSWORDNOTCORRECT,
+		}
+	}
+
+	if code := account.RestrictionCode(); code != 0 {
+		return nil, &RouterError{
+			Message:      fmt.Sprintf("account is restricted: '%s'", account.Status.Reason),
+			ResponseCode: code,
+		}
+	}
+
+	if policy, ok := client.Server.Versions.Get().LoginPolicy(); ok {
+		if code := policy.Check(version); code != 0 {
+			return nil, &RouterError{
+				Message:      fmt.Sprintf("client version '%s' is not accepted", version),
+				ResponseCode: code,
+			}
+		}
+	}
 
-	if player := client.Server.Players.ByName(username); player != nil {
+	if player := client.Server.Players.ByName(account.Name); player != nil {
 		// Player already logged in
 		return nil, &RouterError{
 			Message:      "player already logged in",
@@ -130,24 +271,22 @@ func handleLogin(message *common.GSMessage, client *Client) (*common.GSMessage,
 
 	// Create initial player object
 	player := &Player{
-		Name:    username,
+		Id:      account.Id,
+		Name:    account.Name,
 		Version: version,
-		Info:    Info{Public: public},
+		Info:    account.Info,
 	}
 
-	// Add player to pending waitmodule logins
-	ipAddress := strings.Split(client.Conn.RemoteAddr().String(), ":")[0]
-	client.Server.Pending[ipAddress] = player
+	// The public flag is chosen by the client on every login
+	player.Info.Public = public
 
-	// Remove pending login after 5 seconds
-	time.AfterFunc(5*time.Second, func() {
-		delete(client.Server.Pending, ipAddress)
-	})
+	// Add player to pending waitmodule logins
+	token := client.Server.Pending.Add(player, client.IpAddress())
 
 	response := common.NewGSMessageFromRequest(message)
 	response.Property = common.GSM_PROPERTY_GS
 	response.Type = GSM_GSSUCCESS
-	response.Data = []interface{}{common.WriteU8(GSM_LOGIN)}
+	response.Data = []interface{}{common.WriteU8(GSM_LOGIN), token}
 	return response, nil
 }
 
@@ -173,31 +312,52 @@ func handleWaitModuleLogin(message *common.GSMessage, client *Client) (*common.G
 		return nil, &RouterError{Message: err.Error()}
 	}
 
-	if player := client.Server.Players.ByName(username); player != nil {
-		// Player already logged in
+	var player *Player
+
+	if token, err := common.GetStringListItem(message.Data, 1); err == nil {
+		player = client.Server.Pending.Take(token, username)
+	}
+
+	if player == nil {
+		// Fallback for clients that are not able to carry the login token,
+		// which may send other data in place of it
+		player = client.Server.Pending.TakeByAddress(client.IpAddress(), username)
+	}
+
+	if player == nil {
 		return nil, &RouterError{
-			Message:      "player already logged in",
-			ResponseCode: ERRORROUTER_NOTDISCONNECTED,
+			Message:      "player not found in pending login list",
+			ResponseCode: ERRORROUTER_NOTREGISTERED,
 		}
 	}
 
-	ipAddress := strings.Split(client.Conn.RemoteAddr().String(), ":")[0]
-	player, ok := client.Server.Pending[ipAddress]
-
-	if !ok {
-		return nil, &LobbyError{Message: "ip not found in pending login list"}
+	// The account may have been restricted in the meantime
+	account, err := client.Server.Accounts.Get(player.Name)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_NOTREGISTERED,
+		}
 	}
 
-	if player.Name != username {
-		return nil, &LobbyError{Message: "username mismatch"}
+	if code := account.RestrictionCode(); code != 0 {
+		return nil, &RouterError{
+			Message:      fmt.Sprintf("account is restricted: '%s'", account.Status.Reason),
+			ResponseCode: code,
+		}
 	}
 
-	// Remove pending login
-	delete(client.Server.Pending, ipAddress)
+	player.Client = client
+
+	if !client.Server.Players.Add(player) {
+		// Player already logged in
+		return nil, &RouterError{
+			Message:      "player already logged in",
+			ResponseCode: ERRORROUTER_NOTDISCONNECTED,
+		}
+	}
 
-	player.Client = *client
 	client.Player = player
-	client.Server.Players.Add(player)
 
 	response := common.NewGSMessageFromRequest(message)
 	response.Property = common.GSM_PROPERTY_GS
@@ -207,29 +367,53 @@ func handleWaitModuleLogin(message *common.GSMessage, client *Client) (*common.G
 }
 
 func handlePlayerInfo(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	if client.Player == nil {
+		return nil, &RouterError{
+			ResponseCode: ERRORROUTER_PLAYERNOTCONNECTED,
+			Message:      "player info requested before login",
+		}
+	}
+
 	targetName, err := common.GetStringListItem(message.Data, 0)
 	if err != nil {
 		return nil, &RouterError{Message: err.Error()}
 	}
 
-	player := client.Server.Players.ByName(targetName)
-	if player == nil {
+	account, err := client.Server.Accounts.Get(targetName)
+	if err != nil {
 		return nil, &RouterError{
 			ResponseCode: ERRORROUTER_NOTREGISTERED,
 			Message:      "player was not found",
 		}
 	}
 
-	if !player.Info.Public && player != client.Player {
+	info := account.Info
+	ipAddress := ""
+
+	if player := client.Server.Players.ByName(account.Name); player != nil {
+		info = player.PlayerInfo()
+		ipAddress = player.IpAddress()
+	}
+
+	isOwnProfile := account.Id == client.Player.Id
+
+	if !info.Public && !isOwnProfile {
 		return nil, &RouterError{
 			ResponseCode: ERRORROUTER_NOTREGISTERED,
 			Message:      "player info is not public",
 		}
 	}
 
+	// Email addresses are only shown to their owner
+	email := ""
+
+	if isOwnProfile {
+		email = info.Email
+	}
+
 	playerData := []interface{}{
-		player.Name, player.Info.Surname, player.Info.Firstname,
-		player.Info.Country, player.Info.Email, "IRCID", player.IpAddress(),
+		account.Name, info.Surname, info.Firstname,
+		info.Country, email, "IRCID", ipAddress,
 	}
 
 	response := common.NewGSMessageFromRequest(message)
@@ -271,14 +455,33 @@ func handleLobbyLogin(message *common.GSMessage, client *Client) (*common.GSMess
 		return nil, &LobbyError{Message: "game not supported"}
 	}
 
-	client.Player.Game = gameName
-	response := common.NewGSMessageFromRequest(message)
-	response.Data = []interface{}{
-		strconv.Itoa(GSM_GSSUCCESS),
-		[]interface{}{strconv.Itoa(LOBBY_LOGIN)},
+	policy := client.Server.Versions.Get().Policy(gameName)
+
+	if code := policy.Check(client.Player.CurrentVersion()); code != 0 {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("client version '%s' is not accepted", client.Player.CurrentVersion()),
+			ResponseCode: code,
+		}
 	}
 
-	return response, nil
+	if client.Player.CurrentGame() != gameName {
+		// Groups of the previous game can't be used anymore
+		client.Server.leaveAllGroups(client.Player)
+	}
+
+	lobby := client.Server.Groups.LobbyByGame(gameName)
+
+	if lobby == nil {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("no lobby for game '%s'", gameName),
+			ResponseCode: ERRORLOBBYSRV_GROUPNOTEXIST,
+		}
+	}
+
+	client.Player.SetGame(gameName)
+
+	// The lobby id is needed by the client to join the lobby afterwards
+	return newLobbyResponse(message, LOBBY_LOGIN, strconv.Itoa(lobby.Id)), nil
 }
 
 func handleFriendsLogin(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
@@ -292,12 +495,9 @@ func handleFriendsLogin(message *common.GSMessage, client *Client) (*common.GSMe
 		return nil, &RouterError{Message: err.Error()}
 	}
 
-	client.Player.Friends.Status = status
-	client.Player.Friends.Mood = mood
-
-	// TODO: Load relationships from database
-	client.Player.Friends.List = NewPlayerCollection()
-	client.Player.Friends.Ignored = NewPlayerCollection()
+	client.Player.SetFriendStatus(status, mood)
+	client.Server.notifyFriends(client.Player.Name)
+	client.Server.deliverStoredPages(client.Player)
 
 	response := common.NewGSMessageFromRequest(message)
 	response.Type = GSM_GSSUCCESS
@@ -306,13 +506,20 @@ func handleFriendsLogin(message *common.GSMessage, client *Client) (*common.GSMe
 }
 
 func handleIgnoreListRequest(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
-	ignoredPlayers := client.Player.Friends.Ignored.All()
+	account, err := client.Server.Accounts.Get(client.Player.Name)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
 	ignoredPlayersResponse := []interface{}{}
 
-	for _, player := range ignoredPlayers {
+	for _, name := range account.Ignored {
 		ignoredPlayersResponse = append(
 			ignoredPlayersResponse,
-			player.Name,
+			name,
 		)
 	}
 
@@ -325,23 +532,10 @@ func handleIgnoreListRequest(message *common.GSMessage, client *Client) (*common
 	return response, nil
 }
 
-func handleMotdRequest(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
-	// language, err := common.GetStringListItem(message.Data, 0)
-	response := common.NewGSMessageFromRequest(message)
-	response.Type = GSM_GSSUCCESS
-	response.Data = []interface{}{
-		common.WriteU8(GSM_MOTD_REQUEST),
-		[]interface{}{
-			"Welcome to the server!",  // szUbiMOTD (UBI's MOTD)
-			"This is a test message.", // szGameMOTD (Game's MOTD)
-		},
-	}
-	return response, nil
-}
-
 func init() {
 	RouterHandlers[GSM_STILLALIVE] = stillAlive
 	RouterHandlers[GSM_KEY_EXCHANGE] = handleKeyExchange
+	RouterHandlers[GSM_NEWUSERREQUEST] = handleNewUserRequest
 	RouterHandlers[GSM_LOGIN] = handleLogin
 	RouterHandlers[GSM_JOINWAITMODULE] = handleWaitModuleJoin
 	RouterHandlers[GSM_LOGINWAITMODULE] = handleWaitModuleLogin
@@ -349,7 +543,35 @@ func init() {
 	RouterHandlers[GSM_LOBBY_MSG] = handleLobbyMessage
 	RouterHandlers[GSM_LOGINFRIENDS] = handleFriendsLogin
 	RouterHandlers[GSM_IGNORELIST] = handleIgnoreListRequest
+	RouterHandlers[GSM_ADDFRIEND] = handleAddFriend
+	RouterHandlers[GSM_DELFRIEND] = handleDeleteFriend
+	RouterHandlers[GSM_FRIENDLIST] = handleFriendListRequest
+	RouterHandlers[GSM_STATUSCHANGE] = handleStatusChange
+	RouterHandlers[GSM_ADDIGNOREFRIEND] = handleAddIgnore
+	RouterHandlers[GSM_DELIGNOREFRIEND] = handleDeleteIgnore
+	RouterHandlers[GSM_PAGEPLAYER] = handlePagePlayer
+	RouterHandlers[GSM_PEERMSG] = handlePeerMessage
+	RouterHandlers[GSM_PEERPLAYER] = handlePeerMessage
+	RouterHandlers[GSM_SEARCHPLAYER] = handleSearchPlayer
+	RouterHandlers[GSM_UPDATEPLAYERINFO] = handleUpdatePlayerInfo
+	RouterHandlers[GSM_MODIFYUSER] = handleModifyUser
+	RouterHandlers[GSM_REMOVEACCOUNT] = handleRemoveAccount
+	RouterHandlers[GSM_PHOTO] = handlePhoto
+	RouterHandlers[GSM_NEWS] = handleNewsRequest
+	RouterHandlers[GSM_ADVERTISEMENT] = handleAdvertisementRequest
+	RouterHandlers[GSM_VERSIONLIST] = handleVersionList
+	RouterHandlers[GSM_CHANGEVERSION] = handleChangeVersion
 	RouterHandlers[GSM_MOTD_REQUEST] = handleMotdRequest
 
 	LobbyHandlers[LOBBY_LOGIN] = handleLobbyLogin
+	LobbyHandlers[LOBBY_JOIN_LOBBY] = handleJoinLobby
+	LobbyHandlers[LOBBY_JOIN_ROOM] = handleJoinRoom
+	LobbyHandlers[LOBBY_CREATE_ROOM] = handleCreateRoom
+	LobbyHandlers[LOBBY_GROUP_CONFIG_UPDATE_RES] = handleGroupConfigUpdate
+	LobbyHandlers[LOBBY_PLAYER_KICK] = handlePlayerKick
+	LobbyHandlers[LOBBY_PLAYER_BAN] = handlePlayerBan
+	LobbyHandlers[LOBBY_PLAYER_UNBAN] = handlePlayerUnban
+	LobbyHandlers[LOBBY_PLAYER_BANLIST] = handlePlayerBanList
+	LobbyHandlers[LOBBY_GROUP_LEAVE] = handleGroupLeave
+	LobbyHandlers[LOBBY_PARENT_GROUP_ID] = handleParentGroupId
 }
@@ -0,0 +1,584 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+// Create a successful lobby response for the given sub type
+func newLobbyResponse(request *common.GSMessage, subType int, args ...interface{}) *common.GSMessage {
+	response := common.NewGSMessageFromRequest(request)
+	response.Data = []interface{}{
+		strconv.Itoa(GSM_GSSUCCESS),
+		append([]interface{}{strconv.Itoa(subType)}, args...),
+	}
+	return response
+}
+
+// Create a lobby message, that can be pushed to clients without a request
+func NewLobbyPushMessage(subType int, args ...interface{}) *common.GSMessage {
+	return NewPushMessage(GSM_LOBBY_MSG, []interface{}{strconv.Itoa(subType), args})
+}
+
+// Get the group id, that is passed as the first lobby request argument
+func getLobbyGroup(message *common.GSMessage, client *Client) (*Group, []interface{}, GSError) {
+	requestArgs, err := common.GetListItem(message.Data, 1)
+	if err != nil {
+		return nil, nil, &LobbyError{Message: err.Error()}
+	}
+
+	groupId, err := common.GetIntListItem(requestArgs, 0)
+	if err != nil {
+		return nil, nil, &LobbyError{Message: err.Error()}
+	}
+
+	group := client.Server.Groups.ByID(groupId)
+	if group == nil {
+		return nil, nil, &LobbyError{
+			Message:      fmt.Sprintf("group '%d' does not exist", groupId),
+			ResponseCode: ERRORLOBBYSRV_GROUPNOTEXIST,
+		}
+	}
+
+	return group, requestArgs, nil
+}
+
+// Optional string arguments, e.g. passwords, default to an empty string
+func getOptionalString(args []interface{}, index int) string {
+	value, err := common.GetStringListItem(args, index)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+func isValidRoomName(name string) bool {
+	if strings.TrimSpace(name) == "" || len(name) > ROOM_MAX_NAME_LENGTH {
+		return false
+	}
+
+	for _, char := range name {
+		if unicode.IsControl(char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Send a lobby message to all members of a group
+func (router *Router) broadcastToGroup(group *Group, subType int, args ...interface{}) {
+	for _, player := range group.Members.All() {
+		if err := player.Send(NewLobbyPushMessage(subType, args...)); err != nil {
+			router.Logger.Warning(fmt.Sprintf("Failed to send lobby update to '%s': %s", player.Name, err))
+		}
+	}
+}
+
+// Notify the lobby of a room about changes, so that room browsers stay current
+func (router *Router) broadcastToParent(room *Group, subType int, args ...interface{}) {
+	if lobby := router.Groups.ByID(room.ParentId); lobby != nil {
+		router.broadcastToGroup(lobby, subType, args...)
+	}
+}
+
+func isValidRoomSize(maxPlayers int, maxSpectators int) bool {
+	return maxPlayers >= 1 && maxPlayers <= ROOM_MAX_PLAYERS &&
+		maxSpectators >= 0 && maxSpectators <= ROOM_MAX_SPECTATORS
+}
+
+func (router *Router) joinGroup(player *Player, group *Group) GSError {
+	if code := group.AddMember(player); code != 0 {
+		return &LobbyError{
+			Message:      fmt.Sprintf("'%s' failed to join group '%d'", player.Name, group.Id),
+			ResponseCode: code,
+		}
+	}
+
+	player.setGroup(group, group)
+
+	if group.IsRoom() {
+		router.broadcastToParent(group, LOBBY_MEMBER_JOIN, strconv.Itoa(group.Id), player.Name)
+	}
+
+	return nil
+}
+
+// Remove a player from a group, leaving the room first in case of a lobby.
+// Rooms are removed as soon as their last member has left.
+func (router *Router) leaveGroup(player *Player, group *Group) bool {
+	if group.IsLobby() {
+		if room := player.CurrentRoom(); room != nil && room.ParentId == group.Id {
+			router.leaveGroup(player, room)
+		}
+	}
+
+	removed, wasMaster := group.RemoveMember(player)
+	if !removed {
+		return false
+	}
+
+	player.setGroup(group, nil)
+
+	if !group.IsRoom() {
+		return true
+	}
+
+	router.broadcastToParent(group, LOBBY_MEMBER_LEAVE, strconv.Itoa(group.Id), player.Name)
+
+	if wasMaster {
+		router.migrateMaster(group)
+	}
+
+	if group.CloseIfEmpty() {
+		router.Groups.Remove(group)
+		router.broadcastToParent(group, LOBBY_GROUP_REMOVE, strconv.Itoa(group.Id))
+	}
+
+	return true
+}
+
+// Hand the room over to the next member after the master has left,
+// or close it entirely if host migration is disabled
+func (router *Router) migrateMaster(room *Group) {
+	if router.DisableHostMigration {
+		router.closeRoom(room)
+		return
+	}
+
+	master := room.ElectMaster()
+	if master == nil {
+		return
+	}
+
+	roomId := strconv.Itoa(room.Id)
+
+	for _, member := range room.Members.All() {
+		if member == master {
+			continue
+		}
+
+		if err := member.Send(NewLobbyPushMessage(LOBBY_MASTER_CHANGED, roomId, master.Name)); err != nil {
+			router.Logger.Warning(fmt.Sprintf("Failed to send lobby update to '%s': %s", member.Name, err))
+		}
+	}
+
+	if err := master.Send(NewLobbyPushMessage(LOBBY_MASTER_NEW, roomId)); err != nil {
+		router.Logger.Warning(fmt.Sprintf("Failed to send lobby update to '%s': %s", master.Name, err))
+	}
+}
+
+// Remove all members from a room and notify its lobby about the removal
+func (router *Router) closeRoom(room *Group) {
+	for _, member := range room.Close() {
+		member.setGroup(room, nil)
+	}
+
+	router.Groups.Remove(room)
+	router.broadcastToParent(room, LOBBY_GROUP_REMOVE, strconv.Itoa(room.Id))
+}
+
+// Returns an error, if the player is not the master of the group
+func checkMaster(group *Group, player *Player) GSError {
+	if group.IsMaster(player) {
+		return nil
+	}
+
+	return &LobbyError{
+		Message:      fmt.Sprintf("'%s' is not the master of group '%d'", player.Name, group.Id),
+		ResponseCode: ERRORLOBBYSRV_NOTMASTER,
+	}
+}
+
+func (router *Router) leaveAllGroups(player *Player) {
+	if room := player.CurrentRoom(); room != nil {
+		router.leaveGroup(player, room)
+	}
+
+	if lobby := player.CurrentLobby(); lobby != nil {
+		router.leaveGroup(player, lobby)
+	}
+}
+
+func handleJoinLobby(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	lobby, _, gsError := getLobbyGroup(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	if !lobby.IsLobby() {
+		return nil, &LobbyError{
+			Message:      "group is not a lobby",
+			ResponseCode: ERRORLOBBYSRV_WRONGGROUPTYPE,
+		}
+	}
+
+	if lobby.Game != client.Player.CurrentGame() {
+		return nil, &LobbyError{
+			Message:      "lobby belongs to a different game",
+			ResponseCode: ERRORLOBBYSRV_GAMENOTALLOWED,
+		}
+	}
+
+	if current := client.Player.CurrentLobby(); current != nil && current != lobby {
+		client.Server.leaveGroup(client.Player, current)
+	}
+
+	if gsError := client.Server.joinGroup(client.Player, lobby); gsError != nil {
+		return nil, gsError
+	}
+
+	// Players joining later only get the rooms through this snapshot,
+	// since LOBBY_NEW_GROUP is only sent when a room gets created
+	rooms := make([]interface{}, 0)
+
+	for _, room := range client.Server.Groups.Children(lobby.Id) {
+		rooms = append(rooms, room.Entry())
+	}
+
+	return newLobbyResponse(message, LOBBY_JOIN_LOBBY, strconv.Itoa(lobby.Id), rooms), nil
+}
+
+func handleJoinRoom(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	room, requestArgs, gsError := getLobbyGroup(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	if !room.IsRoom() {
+		return nil, &LobbyError{
+			Message:      "group is not a room",
+			ResponseCode: ERRORLOBBYSRV_WRONGGROUPTYPE,
+		}
+	}
+
+	if lobby := client.Player.CurrentLobby(); lobby == nil || lobby.Id != room.ParentId {
+		return nil, &LobbyError{
+			Message:      "player is not in the parent lobby of the room",
+			ResponseCode: ERRORLOBBYSRV_NOTINGROUP,
+		}
+	}
+
+	if client.Player.CurrentRoom() != nil {
+		return nil, &LobbyError{
+			Message:      "player is already in a room",
+			ResponseCode: ERRORLOBBYSRV_ALREADYINGROUP,
+		}
+	}
+
+	if room.Version != client.Player.CurrentVersion() {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("room '%d' requires version '%s'", room.Id, room.Version),
+			ResponseCode: ERRORLOBBYSRV_WRONGGAMEVERSION,
+		}
+	}
+
+	if room.HasPassword() && !room.CheckPassword(getOptionalString(requestArgs, 1)) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("wrong password for room '%d'", room.Id),
+			ResponseCode: ERRORLOBBYSRV_PASSWORDNOTCORRECT,
+		}
+	}
+
+	if gsError := client.Server.joinGroup(client.Player, room); gsError != nil {
+		return nil, gsError
+	}
+
+	return newLobbyResponse(message, LOBBY_JOIN_ROOM, strconv.Itoa(room.Id)), nil
+}
+
+// Request arguments: lobby id, name, game type, max players,
+// max spectators, game version and an optional password
+func handleCreateRoom(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	lobby, requestArgs, gsError := getLobbyGroup(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	if !lobby.IsLobby() {
+		return nil, &LobbyError{
+			Message:      "rooms can only be created inside of a lobby",
+			ResponseCode: ERRORLOBBYSRV_WRONGGROUPTYPE,
+		}
+	}
+
+	if client.Player.CurrentLobby() != lobby || client.Player.CurrentRoom() != nil {
+		return nil, &LobbyError{
+			Message:      "player has to be in the lobby, but not inside of a room",
+			ResponseCode: ERRORLOBBYSRV_CREATENOTALLOWED,
+		}
+	}
+
+	name, err := common.GetStringListItem(requestArgs, 1)
+	if err != nil {
+		return nil, &LobbyError{Message: err.Error()}
+	}
+
+	if !isValidRoomName(name) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("invalid room name '%s'", name),
+			ResponseCode: ERRORLOBBYSRV_INVALIDGROUPNAME,
+		}
+	}
+
+	gameType, err := common.GetStringListItem(requestArgs, 2)
+	if err != nil {
+		return nil, &LobbyError{Message: err.Error()}
+	}
+
+	maxPlayers, err := common.GetIntListItem(requestArgs, 3)
+	if err != nil {
+		return nil, &LobbyError{Message: err.Error()}
+	}
+
+	maxSpectators, err := common.GetIntListItem(requestArgs, 4)
+	if err != nil {
+		return nil, &LobbyError{Message: err.Error()}
+	}
+
+	if !isValidRoomSize(maxPlayers, maxSpectators) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("invalid room size (%d players, %d spectators)", maxPlayers, maxSpectators),
+			ResponseCode: ERRORLOBBYSRV_CREATENOTALLOWED,
+		}
+	}
+
+	version, err := common.GetStringListItem(requestArgs, 5)
+	if err != nil {
+		return nil, &LobbyError{Message: err.Error()}
+	}
+
+	if version != client.Player.CurrentVersion() {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("room version '%s' does not match the client version", version),
+			ResponseCode: ERRORLOBBYSRV_WRONGGAMEVERSION,
+		}
+	}
+
+	room := &Group{
+		ParentId:      lobby.Id,
+		Type:          GROUP_TYPE_ROOM,
+		Name:          name,
+		Game:          lobby.Game,
+		MaxMembers:    maxPlayers,
+		Members:       NewPlayerCollection(),
+		GameType:      gameType,
+		Version:       version,
+		MaxSpectators: maxSpectators,
+		Password:      getOptionalString(requestArgs, 6),
+		Creator:       client.Player.Name,
+	}
+
+	// The creator is added before the room gets published,
+	// so that it can't be closed by anyone else in the meantime
+	room.AddMember(client.Player)
+
+	if !client.Server.Groups.AddRoom(room) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("room '%s' already exists", name),
+			ResponseCode: ERRORLOBBYSRV_GROUPALREADYEXIST,
+		}
+	}
+
+	client.Player.setGroup(room, room)
+	client.Server.broadcastToGroup(lobby, LOBBY_NEW_GROUP, room.Entry())
+
+	client.Server.Logger.Info(fmt.Sprintf("'%s' created room '%s' (%d)", client.Player.Name, room.Name, room.Id))
+
+	return newLobbyResponse(message, LOBBY_CREATE_ROOM, strconv.Itoa(room.Id)), nil
+}
+
+// Request arguments: room id, max players, max spectators and an
+// optional password. Only the master of the room may change these.
+func handleGroupConfigUpdate(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	room, requestArgs, gsError := getLobbyGroup(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	if !room.IsRoom() {
+		return nil, &LobbyError{
+			Message:      "group is not a room",
+			ResponseCode: ERRORLOBBYSRV_WRONGGROUPTYPE,
+		}
+	}
+
+	if gsError := checkMaster(room, client.Player); gsError != nil {
+		return nil, gsError
+	}
+
+	maxPlayers, err := common.GetIntListItem(requestArgs, 1)
+	if err != nil {
+		return nil, &LobbyError{Message: err.Error()}
+	}
+
+	maxSpectators, err := common.GetIntListItem(requestArgs, 2)
+	if err != nil {
+		return nil, &LobbyError{Message: err.Error()}
+	}
+
+	if !isValidRoomSize(maxPlayers, maxSpectators) || !room.Configure(maxPlayers, maxSpectators, getOptionalString(requestArgs, 3)) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("invalid room size (%d players, %d spectators)", maxPlayers, maxSpectators),
+			ResponseCode: ERRORLOBBYSRV_CREATENOTALLOWED,
+		}
+	}
+
+	client.Server.broadcastToParent(room, LOBBY_GROUP_CONFIG_UPDATE, room.Entry())
+	return newLobbyResponse(message, LOBBY_GROUP_CONFIG_UPDATE_RES, strconv.Itoa(room.Id)), nil
+}
+
+// Get the room and the target player name for moderation requests,
+// which can only be made by the master of the room
+func getModeratedRoom(message *common.GSMessage, client *Client) (*Group, string, GSError) {
+	room, requestArgs, gsError := getLobbyGroup(message, client)
+	if gsError != nil {
+		return nil, "", gsError
+	}
+
+	if !room.IsRoom() {
+		return nil, "", &LobbyError{
+			Message:      "group is not a room",
+			ResponseCode: ERRORLOBBYSRV_WRONGGROUPTYPE,
+		}
+	}
+
+	if gsError := checkMaster(room, client.Player); gsError != nil {
+		return nil, "", gsError
+	}
+
+	return room, getOptionalString(requestArgs, 1), nil
+}
+
+// Remove a player from a room, and let them know that they were kicked
+func (router *Router) kickFromRoom(player *Player, room *Group) bool {
+	if !router.leaveGroup(player, room) {
+		return false
+	}
+
+	if err := player.Send(NewLobbyPushMessage(LOBBY_KICK_OUT, strconv.Itoa(room.Id))); err != nil {
+		router.Logger.Warning(fmt.Sprintf("Failed to send lobby update to '%s': %s", player.Name, err))
+	}
+
+	return true
+}
+
+func handlePlayerKick(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	room, targetName, gsError := getModeratedRoom(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	target := room.Members.ByName(targetName)
+	if target == nil || target == client.Player {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("'%s' can't be kicked from room '%d'", targetName, room.Id),
+			ResponseCode: ERRORLOBBYSRV_MEMBERNOTFOUND,
+		}
+	}
+
+	if !client.Server.kickFromRoom(target, room) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("'%s' already left room '%d'", targetName, room.Id),
+			ResponseCode: ERRORLOBBYSRV_MEMBERNOTFOUND,
+		}
+	}
+
+	return newLobbyResponse(message, LOBBY_PLAYER_KICK, strconv.Itoa(room.Id), target.Name), nil
+}
+
+func handlePlayerBan(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	room, targetName, gsError := getModeratedRoom(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	if targetName == "" || strings.EqualFold(targetName, client.Player.Name) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("'%s' can't be banned from room '%d'", targetName, room.Id),
+			ResponseCode: ERRORLOBBYSRV_MEMBERNOTFOUND,
+		}
+	}
+
+	if !room.Ban(targetName) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("'%s' is already banned from room '%d'", targetName, room.Id),
+			ResponseCode: ERRORLOBBYSRV_MEMBERBANNED,
+		}
+	}
+
+	roomId := strconv.Itoa(room.Id)
+
+	if target := room.Members.ByName(targetName); target != nil {
+		target.Send(NewLobbyPushMessage(LOBBY_PLAYER_BANNED, roomId, target.Name))
+		client.Server.kickFromRoom(target, room)
+	}
+
+	client.Server.broadcastToGroup(room, LOBBY_PLAYER_BANNED, roomId, targetName)
+	return newLobbyResponse(message, LOBBY_PLAYER_BAN, roomId, targetName), nil
+}
+
+func handlePlayerUnban(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	room, targetName, gsError := getModeratedRoom(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	if !room.Unban(targetName) {
+		return nil, &LobbyError{
+			Message:      fmt.Sprintf("'%s' is not banned from room '%d'", targetName, room.Id),
+			ResponseCode: ERRORLOBBYSRV_MEMBERNOTFOUND,
+		}
+	}
+
+	return newLobbyResponse(message, LOBBY_PLAYER_UNBAN, strconv.Itoa(room.Id), targetName), nil
+}
+
+func handlePlayerBanList(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	room, _, gsError := getModeratedRoom(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	names := make([]interface{}, 0)
+
+	for _, name := range room.BanList() {
+		names = append(names, name)
+	}
+
+	return newLobbyResponse(message, LOBBY_PLAYER_BANLIST, strconv.Itoa(room.Id), names), nil
+}
+
+func handleGroupLeave(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	group, _, gsError := getLobbyGroup(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	if !client.Server.leaveGroup(client.Player, group) {
+		return nil, &LobbyError{
+			Message:      "player is not in the group",
+			ResponseCode: ERRORLOBBYSRV_NOTINGROUP,
+		}
+	}
+
+	return newLobbyResponse(message, LOBBY_GROUP_LEAVE, strconv.Itoa(group.Id)), nil
+}
+
+func handleParentGroupId(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	group, _, gsError := getLobbyGroup(message, client)
+	if gsError != nil {
+		return nil, gsError
+	}
+
+	return newLobbyResponse(
+		message,
+		LOBBY_PARENT_GROUP_ID,
+		strconv.Itoa(group.Id),
+		strconv.Itoa(group.ParentId),
+	), nil
+}
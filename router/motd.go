@@ -0,0 +1,76 @@
+package router
+
+import (
+	"strings"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+const MOTD_DEFAULT_LANGUAGE = "en"
+const MOTD_DEFAULT_GAME = "default"
+
+type MotdConfig struct {
+	// Language code -> Ubi MOTD
+	Ubi map[string]string
+
+	// Product ID -> language code -> game MOTD, where
+	// MOTD_DEFAULT_GAME is used for games without an entry
+	Games map[string]map[string]string
+}
+
+func DefaultMotdConfig() MotdConfig {
+	return MotdConfig{
+		Ubi: map[string]string{
+			MOTD_DEFAULT_LANGUAGE: "Welcome to the server!",
+		},
+		Games: map[string]map[string]string{
+			MOTD_DEFAULT_GAME: {MOTD_DEFAULT_LANGUAGE: "This is a test message."},
+		},
+	}
+}
+
+// Look up a message by language, falling back to english
+func localizedMessage(messages map[string]string, language string) string {
+	if message, ok := messages[strings.ToLower(language)]; ok {
+		return message
+	}
+
+	return messages[MOTD_DEFAULT_LANGUAGE]
+}
+
+func (config *MotdConfig) UbiMotd(language string) string {
+	return localizedMessage(config.Ubi, language)
+}
+
+func (config *MotdConfig) GameMotd(game string, language string) string {
+	messages, ok := config.Games[game]
+
+	if !ok {
+		messages = config.Games[MOTD_DEFAULT_GAME]
+	}
+
+	return localizedMessage(messages, language)
+}
+
+func handleMotdRequest(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	// Language is optional
+	language, _ := common.GetStringListItem(message.Data, 0)
+
+	game := ""
+	if client.Player != nil {
+		game = client.Player.CurrentGame()
+	}
+
+	config := client.Server.Motd.Get()
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{
+		common.WriteU8(GSM_MOTD_REQUEST),
+		[]interface{}{
+			config.UbiMotd(language),        // szUbiMOTD (UBI's MOTD)
+			config.GameMotd(game, language), // szGameMOTD (Game's MOTD)
+		},
+	}
+	return response, nil
+}
@@ -0,0 +1,195 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+type NewsItem struct {
+	Id        int
+	Game      string // Empty for all games
+	Language  string // Empty for all languages
+	Title     string
+	Body      string
+	Published time.Time // Items are hidden until they are published
+}
+
+func (item *NewsItem) IsPublished(now time.Time) bool {
+	return !item.Published.After(now)
+}
+
+func (item *NewsItem) Matches(game string, language string) bool {
+	if item.Game != "" && item.Game != game {
+		return false
+	}
+
+	return item.Language == "" || strings.EqualFold(item.Language, language)
+}
+
+func (item *NewsItem) Entry() []interface{} {
+	return []interface{}{
+		strconv.Itoa(item.Id),
+		item.Title,
+		item.Body,
+		item.Published.UTC().Format("2006-01-02 15:04:05"),
+	}
+}
+
+// Keeps track of news items, that players were already notified about
+type NewsNotifier struct {
+	mutex sync.Mutex
+	seen  map[int]bool
+}
+
+func NewNewsNotifier(items []NewsItem) *NewsNotifier {
+	notifier := &NewsNotifier{seen: make(map[int]bool)}
+	now := time.Now()
+
+	for _, item := range items {
+		if item.IsPublished(now) {
+			notifier.seen[item.Id] = true
+		}
+	}
+
+	return notifier
+}
+
+// Return all published items, that were not announced yet.
+// Items without a unique id can't be told apart, and are skipped.
+func (notifier *NewsNotifier) Unannounced(items []NewsItem) []NewsItem {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	now := time.Now()
+	result := []NewsItem{}
+	invalid := invalidNewsIds(items)
+
+	for _, item := range items {
+		if invalid[item.Id] {
+			continue
+		}
+
+		if item.IsPublished(now) && !notifier.seen[item.Id] {
+			notifier.seen[item.Id] = true
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// Get all ids, that are either zero or used by more than one item
+func invalidNewsIds(items []NewsItem) map[int]bool {
+	counts := make(map[int]int)
+	invalid := make(map[int]bool)
+
+	for _, item := range items {
+		counts[item.Id]++
+
+		if item.Id == 0 || counts[item.Id] > 1 {
+			invalid[item.Id] = true
+		}
+	}
+
+	return invalid
+}
+
+// Get all published news for a game, with the newest items first. If there
+// are no items in the requested language, the english ones are used instead.
+func (router *Router) newsFor(game string, language string) []NewsItem {
+	items := router.News.Get()
+	now := time.Now()
+
+	filter := func(language string) []NewsItem {
+		result := []NewsItem{}
+
+		for _, item := range items {
+			if item.IsPublished(now) && item.Matches(game, language) {
+				result = append(result, item)
+			}
+		}
+
+		return result
+	}
+
+	result := filter(language)
+
+	if len(result) == 0 {
+		result = filter(MOTD_DEFAULT_LANGUAGE)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Published.After(result[j].Published)
+	})
+
+	return result
+}
+
+// Push new items to online players, after they were added to the news
+// file or once their publish time was reached
+func (router *Router) announceNews() {
+	items := router.News.Get()
+
+	if generation := router.News.Generation(); generation != router.newsGeneration {
+		router.newsGeneration = generation
+
+		for id := range invalidNewsIds(items) {
+			router.Logger.Warning(fmt.Sprintf("News items with id '%d' are ignored, ids must be unique and not zero", id))
+		}
+	}
+
+	unannounced := router.NewsNotifier.Unannounced(items)
+
+	if len(unannounced) == 0 {
+		return
+	}
+
+	for _, player := range router.Players.All() {
+		// Use the same language fallback as for GSM_NEWS requests
+		visible := router.newsFor(player.CurrentGame(), player.CurrentLanguage())
+
+		for _, item := range unannounced {
+			if containsNewsItem(visible, item.Id) {
+				player.Send(NewPushMessage(GSM_UPDATENEWS, []interface{}{item.Entry()}))
+			}
+		}
+	}
+}
+
+func containsNewsItem(items []NewsItem, id int) bool {
+	for _, item := range items {
+		if item.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func handleNewsRequest(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	// Language is optional
+	language, _ := common.GetStringListItem(message.Data, 0)
+
+	if language == "" {
+		language = MOTD_DEFAULT_LANGUAGE
+	}
+
+	// Remembered for pushing news, that are published later on
+	client.Player.SetLanguage(language)
+
+	items := []interface{}{}
+
+	for _, item := range client.Server.newsFor(client.Player.CurrentGame(), language) {
+		items = append(items, item.Entry())
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_NEWS), items}
+	return response, nil
+}
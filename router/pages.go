@@ -0,0 +1,175 @@
+package router
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+	"golang.org/x/time/rate"
+)
+
+const PAGE_MAX_LENGTH = 256
+const PAGE_MAX_STORED = 50
+const PAGE_RATE_INTERVAL = 2 * time.Second
+const PAGE_RATE_BURST = 5
+
+// Rate limiters for sending pages, kept per account
+// so that reconnecting does not reset them
+type PageLimiter struct {
+	mutex    sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func NewPageLimiter() *PageLimiter {
+	return &PageLimiter{limiters: make(map[string]*rate.Limiter)}
+}
+
+// Remove the limiter of an account, e.g. after it was deleted
+func (limiter *PageLimiter) Forget(sender string) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	delete(limiter.limiters, accountKey(sender))
+}
+
+func (limiter *PageLimiter) Allow(sender string) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	key := accountKey(sender)
+	entry, ok := limiter.limiters[key]
+
+	if !ok {
+		entry = rate.NewLimiter(rate.Every(PAGE_RATE_INTERVAL), PAGE_RATE_BURST)
+		limiter.limiters[key] = entry
+	}
+
+	return entry.Allow()
+}
+
+func newPagerMessage(page Page) *common.GSMessage {
+	return NewPushMessage(GSM_PAGER, []interface{}{
+		page.Sender,
+		page.Message,
+		page.SentAt.UTC().Format("2006-01-02 15:04:05"),
+	})
+}
+
+// Send all pages that were stored while the player was offline.
+// Pages are only removed from the account once they were sent.
+func (router *Router) deliverStoredPages(player *Player) {
+	account, err := router.Accounts.Get(player.Name)
+	if err != nil {
+		router.Logger.Error(fmt.Sprintf("Failed to load stored pages for '%s': %s", player.Name, err))
+		return
+	}
+
+	delivered := make([]Page, 0, len(account.Pages))
+
+	for _, page := range account.Pages {
+		if err := player.Send(newPagerMessage(page)); err != nil {
+			router.Logger.Warning(fmt.Sprintf("Failed to deliver page to '%s': %s", player.Name, err))
+			break
+		}
+		delivered = append(delivered, page)
+	}
+
+	if len(delivered) == 0 {
+		return
+	}
+
+	err = router.Accounts.Modify(player.Name, func(account *Account) error {
+		account.Pages = removePages(account.Pages, delivered)
+		return nil
+	})
+
+	if err != nil {
+		router.Logger.Error(fmt.Sprintf("Failed to remove delivered pages for '%s': %s", player.Name, err))
+	}
+}
+
+// Remove the given pages, keeping any that were stored in the meantime
+func removePages(pages []Page, removed []Page) []Page {
+	remaining := make([]Page, 0, len(pages))
+
+	for _, page := range pages {
+		if !containsPage(removed, page) {
+			remaining = append(remaining, page)
+		}
+	}
+
+	return remaining
+}
+
+func containsPage(pages []Page, page Page) bool {
+	for _, other := range pages {
+		if other.Sender == page.Sender && other.Message == page.Message && other.SentAt.Equal(page.SentAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func handlePagePlayer(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	targetName, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	text, err := common.GetStringListItem(message.Data, 1)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	if len(text) > PAGE_MAX_LENGTH {
+		text = text[:PAGE_MAX_LENGTH]
+	}
+
+	target, err := client.Server.Accounts.Get(targetName)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      "page target not found",
+			ResponseCode: ERRORROUTER_PLAYERNOTREGISTERED,
+		}
+	}
+
+	if gsError := client.Server.checkIgnored(target.Name, client.Player.Name); gsError != nil {
+		return nil, gsError
+	}
+
+	if !client.Server.PageLimiter.Allow(client.Player.Name) {
+		return nil, &RouterError{Message: fmt.Sprintf("'%s' is sending pages too quickly", client.Player.Name)}
+	}
+
+	page := Page{
+		Sender:  client.Player.Name,
+		Message: text,
+		SentAt:  time.Now(),
+	}
+
+	player := client.Server.Players.ByName(target.Name)
+	delivered := player != nil && player.FriendsLoggedIn() && player.Send(newPagerMessage(page)) == nil
+
+	if !delivered {
+		// Store the page until the player logs in to the friends service
+		err = client.Server.Accounts.Modify(target.Name, func(account *Account) error {
+			if len(account.Pages) >= PAGE_MAX_STORED {
+				account.Pages = account.Pages[1:]
+			}
+			account.Pages = append(account.Pages, page)
+			return nil
+		})
+	}
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_PAGEPLAYER)}
+	return response, nil
+}
@@ -0,0 +1,40 @@
+package router
+
+import "github.com/lekuruu/ubisoft-game-service/common"
+
+// Relay a GSM_PEERMSG or GSM_PEERPLAYER message to another online player,
+// with the target name in the first item being replaced by the sender's name
+func handlePeerMessage(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	targetName, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	target := client.Server.Players.ByName(targetName)
+	if target == nil {
+		return nil, &RouterError{
+			Message:      "peer target is not online",
+			ResponseCode: ERRORFRIENDS_PLAYERNOTONLINE,
+		}
+	}
+
+	if gsError := client.Server.checkIgnored(target.Name, client.Player.Name); gsError != nil {
+		return nil, gsError
+	}
+
+	data := []interface{}{client.Player.Name}
+	data = append(data, message.Data[1:]...)
+
+	err = target.Send(NewPushMessage(message.Type, data))
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORFRIENDS_PLAYERNOTONLINE,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(message.Type)}
+	return response, nil
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const PENDING_LOGIN_TIMEOUT = 15 * time.Second
+
+type PendingLogin struct {
+	Token   string
+	Address string
+	Player  *Player
+	Expiry  time.Time
+}
+
+// PendingLogins keeps track of players that have logged in to the router,
+// but did not log in to the WaitModule yet. Every login attempt gets its
+// own token, so that multiple players behind the same address don't
+// overwrite each other.
+type PendingLogins struct {
+	mutex     sync.Mutex
+	timeout   time.Duration
+	tokens    map[string]*PendingLogin
+	addresses map[string]string
+}
+
+func NewPendingLogins(timeout time.Duration) *PendingLogins {
+	return &PendingLogins{
+		timeout:   timeout,
+		tokens:    make(map[string]*PendingLogin),
+		addresses: make(map[string]string),
+	}
+}
+
+// Register a pending login and return its token
+func (pending *PendingLogins) Add(player *Player, address string) string {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	pending.prune()
+
+	key := pendingAddressKey(address, player.Name)
+
+	if previous, ok := pending.addresses[key]; ok {
+		// The same player logged in again from the same address
+		delete(pending.tokens, previous)
+	}
+
+	login := &PendingLogin{
+		Token:   generateToken(),
+		Address: address,
+		Player:  player,
+		Expiry:  time.Now().Add(pending.timeout),
+	}
+
+	pending.tokens[login.Token] = login
+	pending.addresses[key] = login.Token
+	return login.Token
+}
+
+// Remove and return the pending login for the given token,
+// if it was issued to the player with the given username
+func (pending *PendingLogins) Take(token string, username string) *Player {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	pending.prune()
+
+	login, ok := pending.tokens[token]
+	if !ok || accountKey(login.Player.Name) != accountKey(username) {
+		return nil
+	}
+
+	pending.remove(login)
+	return login.Player
+}
+
+// Remove and return the pending login for the given address & username,
+// which is used for clients that are not able to carry a token
+func (pending *PendingLogins) TakeByAddress(address string, username string) *Player {
+	pending.mutex.Lock()
+	defer pending.mutex.Unlock()
+	pending.prune()
+
+	token, ok := pending.addresses[pendingAddressKey(address, username)]
+	if !ok {
+		return nil
+	}
+
+	login := pending.tokens[token]
+	pending.remove(login)
+	return login.Player
+}
+
+// Remove all expired logins, the caller must hold the lock
+func (pending *PendingLogins) prune() {
+	now := time.Now()
+
+	for _, login := range pending.tokens {
+		if now.After(login.Expiry) {
+			pending.remove(login)
+		}
+	}
+}
+
+func (pending *PendingLogins) remove(login *PendingLogin) {
+	delete(pending.tokens, login.Token)
+	delete(pending.addresses, pendingAddressKey(login.Address, login.Player.Name))
+}
+
+func pendingAddressKey(address string, username string) string {
+	return address + "/" + accountKey(username)
+}
+
+func generateToken() string {
+	buffer := make([]byte, 16)
+	rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
@@ -0,0 +1,126 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+const PHOTO_MAX_SIZE = 64 * 1024
+
+var ErrPhotoNotFound = errors.New("photo not found")
+
+var photoFormats = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/bmp":  true,
+}
+
+// PhotoStore saves player avatars as one file per account inside
+// a directory, so that they can also be served by the web server
+type PhotoStore struct {
+	directory string
+}
+
+func NewPhotoStore(directory string) (*PhotoStore, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &PhotoStore{directory: directory}, nil
+}
+
+func (store *PhotoStore) Load(name string) ([]byte, error) {
+	data, err := os.ReadFile(store.path(name))
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrPhotoNotFound
+	}
+
+	return data, err
+}
+
+func (store *PhotoStore) Save(name string, data []byte) error {
+	// Usernames have to start with a letter, so the
+	// temporary file can never be mistaken for a photo
+	temp := store.path("." + name + ".tmp")
+
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(temp, store.path(name))
+}
+
+func (store *PhotoStore) Delete(name string) error {
+	err := os.Remove(store.path(name))
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Account names are validated on registration, so
+// they are safe to be used as file names
+func (store *PhotoStore) path(name string) string {
+	return filepath.Join(store.directory, filepath.Base(accountKey(name)))
+}
+
+// Players can either upload their own photo by sending the image data,
+// or request the photo of another player by sending their name
+func handlePhoto(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	if image, err := common.GetBinaryListItem(message.Data, 0); err == nil {
+		return handlePhotoUpload(message, client, image)
+	}
+
+	targetName, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	account, err := client.Server.Accounts.Get(targetName)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      "player was not found",
+			ResponseCode: ERRORROUTER_PLAYERNOTREGISTERED,
+		}
+	}
+
+	image, err := client.Server.Photos.Load(account.Name)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_PHOTO), account.Name, image}
+	return response, nil
+}
+
+func handlePhotoUpload(message *common.GSMessage, client *Client, image []byte) (*common.GSMessage, GSError) {
+	if len(image) == 0 || len(image) > PHOTO_MAX_SIZE {
+		return nil, &RouterError{Message: "invalid photo size"}
+	}
+
+	if !photoFormats[http.DetectContentType(image)] {
+		return nil, &RouterError{Message: "unsupported photo format"}
+	}
+
+	if err := client.Server.Photos.Save(client.Player.Name, image); err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_PHOTO)}
+	return response, nil
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"strconv"
 	"strings"
+	"sync"
 )
 
 type Info struct {
@@ -15,28 +16,129 @@ type Info struct {
 }
 
 type Friends struct {
-	Status  uint32
-	Mood    uint32
-	Ignored PlayerCollection
-	List    PlayerCollection
+	LoggedIn bool
+	Status   uint32
+	Mood     uint32
 }
 
 type Player struct {
-	Id      int
-	Name    string
-	Game    string
-	Version string
-	Info    Info
-	Friends Friends
-	Client
+	Id       int
+	Name     string
+	Game     string
+	Version  string
+	Language string // Empty until the player requested the news
+	Info     Info
+	Friends  Friends
+	Lobby    *Group
+	Room     *Group
+	*Client
+
+	// Guards fields that are read by other clients
+	mutex sync.RWMutex
+}
+
+func (player *Player) CurrentGame() string {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+	return player.Game
+}
+
+func (player *Player) SetGame(game string) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.Game = game
+}
+
+func (player *Player) CurrentLanguage() string {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+
+	if player.Language == "" {
+		return MOTD_DEFAULT_LANGUAGE
+	}
+
+	return player.Language
+}
+
+func (player *Player) SetLanguage(language string) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.Language = language
+}
+
+func (player *Player) CurrentLobby() *Group {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+	return player.Lobby
+}
+
+func (player *Player) CurrentRoom() *Group {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+	return player.Room
+}
+
+func (player *Player) setGroup(group *Group, value *Group) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+
+	if group.IsLobby() {
+		player.Lobby = value
+	} else {
+		player.Room = value
+	}
+}
+
+func (player *Player) CurrentVersion() string {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+	return player.Version
+}
+
+func (player *Player) SetVersion(version string) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.Version = version
+}
+
+func (player *Player) PlayerInfo() Info {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+	return player.Info
+}
+
+func (player *Player) SetInfo(info Info) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.Info = info
+}
+
+func (player *Player) FriendStatus() (uint32, uint32) {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+	return player.Friends.Status, player.Friends.Mood
+}
+
+func (player *Player) SetFriendStatus(status uint32, mood uint32) {
+	player.mutex.Lock()
+	defer player.mutex.Unlock()
+	player.Friends.LoggedIn = true
+	player.Friends.Status = status
+	player.Friends.Mood = mood
+}
+
+func (player *Player) FriendsLoggedIn() bool {
+	player.mutex.RLock()
+	defer player.mutex.RUnlock()
+	return player.Friends.LoggedIn
 }
 
-func (player *Player) IpAddress() string {
-	return strings.Split(player.Client.Conn.RemoteAddr().String(), ":")[0]
+func (client *Client) IpAddress() string {
+	return strings.Split(client.Conn.RemoteAddr().String(), ":")[0]
 }
 
-func (player *Player) Port() int {
-	portString := strings.Split(player.Client.Conn.RemoteAddr().String(), ":")[1]
+func (client *Client) Port() int {
+	portString := strings.Split(client.Conn.RemoteAddr().String(), ":")[1]
 	port, err := strconv.Atoi(portString)
 
 	if err != nil {
@@ -0,0 +1,99 @@
+package router
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_\-.]*$`)
+
+// AccountPolicy describes the rules that usernames and
+// passwords need to follow when registering an account
+type AccountPolicy struct {
+	MinUsernameLength int
+	MaxUsernameLength int
+	MinPasswordLength int
+	MaxPasswordLength int
+
+	// Usernames containing any of these words are rejected
+	ForbiddenWords []string
+
+	// Usernames that can't be registered by players, e.g. "admin"
+	ReservedNames []string
+}
+
+func DefaultAccountPolicy() AccountPolicy {
+	return AccountPolicy{
+		MinUsernameLength: 3,
+		MaxUsernameLength: 15,
+		MinPasswordLength: 6,
+		MaxPasswordLength: 32,
+		ReservedNames:     []string{"admin", "administrator", "moderator", "server", "ubisoft"},
+	}
+}
+
+// Returns an ERRORSECURE_* code, or zero if the username is valid
+func (policy *AccountPolicy) ValidateUsername(username string) int {
+	if len(username) < policy.MinUsernameLength || len(username) > policy.MaxUsernameLength {
+		return ERRORSECURE_USERNAMEMALFORMED
+	}
+
+	if !usernamePattern.MatchString(username) {
+		return ERRORSECURE_USERNAMEMALFORMED
+	}
+
+	lowercase := strings.ToLower(username)
+
+	for _, name := range policy.ReservedNames {
+		if lowercase == strings.ToLower(name) {
+			return ERRORSECURE_USERNAMERESERVED
+		}
+	}
+
+	for _, word := range policy.ForbiddenWords {
+		if word != "" && strings.Contains(lowercase, strings.ToLower(word)) {
+			return ERRORSECURE_USERNAMEFORBIDDEN
+		}
+	}
+
+	return 0
+}
+
+// Returns an ERRORSECURE_* code, or zero if the password is valid
+func (policy *AccountPolicy) ValidatePassword(username string, password string) int {
+	if len(password) < policy.MinPasswordLength || len(password) > policy.MaxPasswordLength {
+		return ERRORSECURE_PASSWORDMALFORMED
+	}
+
+	for _, char := range password {
+		if char < 0x21 || char > 0x7E {
+			// Only printable ascii characters are allowed
+			return ERRORSECURE_PASSWORDMALFORMED
+		}
+	}
+
+	if strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return ERRORSECURE_PASSWORDFORBIDDEN
+	}
+
+	return 0
+}
+
+// Returns an ERRORSECURE_* code, or zero if the profile is valid.
+// Both the email and the country are optional.
+func (policy *AccountPolicy) ValidateInfo(info Info) int {
+	if info.Email != "" {
+		address, err := mail.ParseAddress(info.Email)
+
+		if err != nil || address.Address != info.Email {
+			return ERRORSECURE_INVALIDACCOUNT
+		}
+	}
+
+	if info.Country != "" && !IsValidCountry(info.Country) {
+		return ERRORSECURE_INVALIDACCOUNT
+	}
+
+	return 0
+}
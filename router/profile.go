@@ -0,0 +1,228 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+func handleUpdatePlayerInfo(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	firstname, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	surname, err := common.GetStringListItem(message.Data, 1)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	country, err := common.GetStringListItem(message.Data, 2)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	email, err := common.GetStringListItem(message.Data, 3)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	info := client.Player.PlayerInfo()
+	info.Firstname = firstname
+	info.Surname = surname
+	info.Country = strings.ToUpper(country)
+	info.Email = email
+
+	// Gender & visibility are optional
+	if gender, err := common.GetBinaryListItem(message.Data, 4); err == nil && len(gender) > 0 {
+		info.Gender = gender[0]
+	}
+
+	if public, err := common.GetBoolListItem(message.Data, 5); err == nil {
+		info.Public = public
+	}
+
+	if code := client.Server.Policy.ValidateInfo(info); code != 0 {
+		return nil, &RouterError{
+			Message:      "invalid player info",
+			ResponseCode: code,
+		}
+	}
+
+	err = client.Server.Accounts.Modify(client.Player.Name, func(account *Account) error {
+		account.Info = info
+		return nil
+	})
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_DATABASEFAILED,
+		}
+	}
+
+	client.Player.SetInfo(info)
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_UPDATEPLAYERINFO)}
+	return response, nil
+}
+
+func handleModifyUser(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	oldPassword, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	newPassword, err := common.GetStringListItem(message.Data, 1)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	if code := client.Server.Policy.ValidatePassword(client.Player.Name, newPassword); code != 0 {
+		return nil, &RouterError{
+			Message:      "invalid password",
+			ResponseCode: code,
+		}
+	}
+
+	account, err := client.Server.Accounts.Get(client.Player.Name)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_DATABASEFAILED,
+		}
+	}
+
+	// Hashing is slow, so it's done before locking the account store
+	if !account.CheckPassword(oldPassword) {
+		return nil, &RouterError{
+			Message:      "password not correct",
+			ResponseCode: ERRORSECURE_INVALIDPASSWORD,
+		}
+	}
+
+	previousHash := account.Password
+
+	if err := account.SetPassword(newPassword); err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_DATABASEFAILED,
+		}
+	}
+
+	err = client.Server.Accounts.Modify(account.Name, func(stored *Account) error {
+		if stored.Password != previousHash {
+			// The password was changed in the meantime
+			return ErrPasswordNotCorrect
+		}
+		stored.Password = account.Password
+		return nil
+	})
+
+	if err == ErrPasswordNotCorrect {
+		return nil, &RouterError{
+			Message:      "password not correct",
+			ResponseCode: ERRORSECURE_INVALIDPASSWORD,
+		}
+	}
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_DATABASEFAILED,
+		}
+	}
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_MODIFYUSER)}
+	return response, nil
+}
+
+// Delete an account and remove every reference to it. The account is
+// deleted first, so that no new references can be created afterwards.
+// Failures during the cleanup are logged, as the account is gone already.
+func (router *Router) removeAccount(name string) error {
+	router.accountRemoval.Lock()
+	defer router.accountRemoval.Unlock()
+
+	if err := router.Accounts.Delete(name); err != nil {
+		return err
+	}
+
+	accounts, err := router.Accounts.Search(AccountQuery{Pattern: "*"})
+	if err != nil {
+		router.Logger.Error(fmt.Sprintf("Failed to remove references to '%s': %s", name, err))
+	}
+
+	for _, account := range accounts {
+		if !account.HasFriend(name) && !account.IsIgnoring(name) && !account.hasPagesFrom(name) {
+			continue
+		}
+
+		err := router.Accounts.Modify(account.Name, func(account *Account) error {
+			account.Friends = removeName(account.Friends, name)
+			account.Ignored = removeName(account.Ignored, name)
+			account.removePagesFrom(name)
+			return nil
+		})
+
+		if err != nil && err != ErrAccountNotFound {
+			router.Logger.Error(fmt.Sprintf("Failed to remove references to '%s' from '%s': %s", name, account.Name, err))
+		}
+	}
+
+	if err := router.Photos.Delete(name); err != nil {
+		router.Logger.Error(fmt.Sprintf("Failed to remove photo of '%s': %s", name, err))
+	}
+
+	for _, group := range router.Groups.All() {
+		group.Unban(name)
+	}
+
+	router.PageLimiter.Forget(name)
+	return nil
+}
+
+func handleRemoveAccount(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	password, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	account, err := client.Server.Accounts.Get(client.Player.Name)
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_DATABASEFAILED,
+		}
+	}
+
+	if !account.CheckPassword(password) {
+		return nil, &RouterError{
+			Message:      "password not correct",
+			ResponseCode: ERRORSECURE_INVALIDPASSWORD,
+		}
+	}
+
+	if err := client.Server.removeAccount(account.Name); err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORSECURE_DATABASEFAILED,
+		}
+	}
+
+	client.Server.Logger.Info(fmt.Sprintf("Removed account '%s'", account.Name))
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_REMOVEACCOUNT)}
+
+	// Close the session once the confirmation was sent
+	client.Send(response)
+	client.Disconnect()
+	return nil, nil
+}
@@ -5,29 +5,56 @@ import (
 	"io"
 	"log"
 	"net"
+	"sync"
+	"time"
 
 	"github.com/lekuruu/ubisoft-game-service/common"
 )
 
 type Router struct {
-	Host    string
-	Port    uint16
-	Games   []string
-	Logger  common.Logger
-	Players PlayerCollection
-	Pending map[string]*Player
-}
-
-type Client struct {
-	Conn   net.Conn
-	Server *Router
-	Player *Player
-	State  *common.GSClientState
+	Host           string
+	Port           uint16
+	Games          []string
+	Logger         common.Logger
+	Accounts       AccountStore
+	Photos         *PhotoStore
+	Policy         AccountPolicy
+	Motd           *ConfigFile[MotdConfig]
+	News           *ConfigFile[[]NewsItem]
+	Advertisements *ConfigFile[[]Advertisement]
+	Versions       *ConfigFile[VersionConfig]
+	Players        *PlayerCollection
+	Groups         *GroupCollection
+	Pending        *PendingLogins
+
+	// Held while an account is being removed, and by every request
+	// that adds a reference to another account
+	accountRemoval sync.RWMutex
+
+	// Close rooms instead of picking a new master, once their master leaves
+	DisableHostMigration bool
+
+	PageLimiter           *PageLimiter
+	NewsNotifier          *NewsNotifier
+	AdvertisementNotifier *AdvertisementNotifier
+
+	// Last version config that was announced to the players
+	versionsGeneration int
+
+	// Last news file that was checked for invalid ids
+	newsGeneration int
 }
 
 func (router *Router) Serve() {
 	router.Players = NewPlayerCollection()
-	router.Pending = make(map[string]*Player)
+	router.Groups = NewGroupCollection()
+	router.Groups.CreateLobbies(router.Games)
+	router.Pending = NewPendingLogins(PENDING_LOGIN_TIMEOUT)
+	router.PageLimiter = NewPageLimiter()
+	router.NewsNotifier = NewNewsNotifier(router.News.Get())
+	router.AdvertisementNotifier = &AdvertisementNotifier{}
+	router.AdvertisementNotifier.Changed(router.Advertisements.Get())
+	router.versionsGeneration = router.Versions.Generation()
 
 	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", router.Host, router.Port))
 
@@ -38,6 +65,7 @@ func (router *Router) Serve() {
 	router.Logger.Info(fmt.Sprintf("Listening on %s:%d", router.Host, router.Port))
 
 	defer listener.Close()
+	go router.pollUpdates()
 
 	for {
 		conn, err := listener.Accept()
@@ -50,14 +78,37 @@ func (router *Router) Serve() {
 	}
 }
 
+// Periodically check the config files for changes, that
+// need to be pushed to the players that are online
+func (router *Router) pollUpdates() {
+	ticker := time.NewTicker(CONFIG_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		router.announceNews()
+		router.announceAdvertisements()
+		router.announceVersions()
+		router.FlushAccounts()
+	}
+}
+
+// Write buffered account changes to disk, if the store supports it
+func (router *Router) FlushAccounts() {
+	store, ok := router.Accounts.(FlushableStore)
+	if !ok {
+		return
+	}
+
+	if err := store.Flush(); err != nil {
+		router.Logger.Error(fmt.Sprintf("Failed to save accounts: %s", err))
+	}
+}
+
 func (router *Router) HandleClient(conn net.Conn) {
 	router.Logger.Info(fmt.Sprintf("-> <%s>", conn.RemoteAddr()))
 
-	client := &Client{
-		Conn:   conn,
-		Server: router,
-		State:  &common.GSClientState{},
-	}
+	client := NewClient(conn, router)
+	go client.writeLoop()
 
 	defer router.OnDisconnect(client)
 
@@ -82,6 +133,10 @@ func (router *Router) HandleClient(conn net.Conn) {
 			continue
 		}
 
+		if client.Player == nil && !PublicHandlers[msg.Type] {
+			handler = requireLogin
+		}
+
 		response, gsError := handler(msg, client)
 
 		if gsError != nil {
@@ -94,21 +149,12 @@ func (router *Router) HandleClient(conn net.Conn) {
 			continue
 		}
 
-		serialized, err := response.Serialize(client.State)
-
-		if err != nil {
-			router.Logger.Error(fmt.Sprintf("Failed to serialize message: %s", err))
-			break
-		}
-
-		_, err = conn.Write(serialized)
+		err = client.Send(response)
 
 		if err != nil {
 			router.Logger.Error(fmt.Sprintf("Failed to send message: %s", err))
 			break
 		}
-
-		router.Logger.Debug(fmt.Sprintf("<- %v", response.String()))
 	}
 }
 
@@ -119,8 +165,15 @@ func (router *Router) OnDisconnect(client *Client) {
 
 	if client.Player != nil {
 		router.Players.Remove(client.Player)
+		router.leaveAllGroups(client.Player)
+
+		if client.Player.FriendsLoggedIn() {
+			router.notifyFriends(client.Player.Name)
+		}
 	}
 
+	client.closeQueue()
+
 	router.Logger.Info(fmt.Sprintf("-> <%s> Disconnected", client.Conn.RemoteAddr()))
 	client.Conn.Close()
 }
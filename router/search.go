@@ -0,0 +1,83 @@
+package router
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+const SEARCH_PAGE_SIZE = 20
+const SEARCH_MAX_RESULTS = 100
+
+// Search registered accounts by name and optionally by country. The results
+// are sent in pages of SEARCH_PAGE_SIZE entries, and the search is always
+// terminated with ERRORFRIENDS_SEARCHPLAYERFINISHED. At most SEARCH_MAX_RESULTS
+// accounts are returned per request, starting at the optional offset. If there
+// are more results, the last page carries the offset to continue the search at.
+func handleSearchPlayer(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	pattern, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	// Country and offset are optional
+	country, _ := common.GetStringListItem(message.Data, 1)
+	offset, _ := common.GetIntListItem(message.Data, 2)
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	// Fetch one more result than needed, to find out if there are more
+	accounts, err := client.Server.Accounts.Search(AccountQuery{
+		Pattern: pattern,
+		Country: country,
+		Offset:  offset,
+		Limit:   SEARCH_MAX_RESULTS + 1,
+	})
+
+	if err != nil {
+		return nil, &RouterError{
+			Message:      err.Error(),
+			ResponseCode: ERRORROUTER_DBPROBLEM,
+		}
+	}
+
+	truncated := len(accounts) > SEARCH_MAX_RESULTS
+
+	if truncated {
+		accounts = accounts[:SEARCH_MAX_RESULTS]
+	}
+
+	for start := 0; start < len(accounts); start += SEARCH_PAGE_SIZE {
+		end := min(start+SEARCH_PAGE_SIZE, len(accounts))
+		results := []interface{}{}
+
+		for _, account := range accounts[start:end] {
+			status, _ := client.Server.visibleStatus(account.Name)
+			results = append(results, []interface{}{account.Name, account.Info.Country, common.WriteU32(status)})
+		}
+
+		response := common.NewGSMessageFromRequest(message)
+		response.Type = GSM_GSSUCCESS
+		response.Data = []interface{}{common.WriteU8(GSM_SEARCHPLAYER), results}
+
+		if truncated && end == len(accounts) {
+			response.Data = append(response.Data, strconv.Itoa(offset+len(accounts)))
+		}
+
+		if err := client.Send(response); err != nil {
+			return nil, &RouterError{Message: err.Error()}
+		}
+	}
+
+	client.Server.Logger.Debug(
+		fmt.Sprintf("Search for '%s' finished with %d results (truncated: %t)", pattern, len(accounts), truncated),
+	)
+
+	// The protocol terminates searches with a failure message, even though
+	// nothing went wrong, so it is sent as a response instead of an error
+	finished := &RouterError{ResponseCode: ERRORFRIENDS_SEARCHPLAYERFINISHED}
+	return finished.Response(message), nil
+}
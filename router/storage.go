@@ -0,0 +1,266 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FileAccountStore keeps all accounts in memory and writes them to a
+// single JSON file inside the data directory. Changes are only marked
+// as dirty, and written to disk once Flush() gets called.
+type FileAccountStore struct {
+	path     string
+	mutex    sync.RWMutex
+	accounts map[string]*Account
+	nextId   int
+	dirty    bool
+
+	// Makes sure that only one flush is writing to the file at a time
+	flushing sync.Mutex
+}
+
+// The next id is stored alongside the accounts, so that
+// ids of deleted accounts are never handed out again
+type accountsFile struct {
+	NextId   int
+	Accounts []*Account
+}
+
+func NewFileAccountStore(directory string) (*FileAccountStore, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	store := &FileAccountStore{
+		path:     filepath.Join(directory, "accounts.json"),
+		accounts: make(map[string]*Account),
+		nextId:   1,
+	}
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *FileAccountStore) Get(name string) (*Account, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	account, ok := store.accounts[accountKey(name)]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	return account.clone(), nil
+}
+
+func (store *FileAccountStore) Create(account *Account) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := accountKey(account.Name)
+
+	if _, ok := store.accounts[key]; ok {
+		return ErrAccountExists
+	}
+
+	copied := account.clone()
+	copied.Id = store.nextId
+	store.accounts[key] = copied
+	store.nextId++
+	store.dirty = true
+
+	account.Id = copied.Id
+	return nil
+}
+
+func (store *FileAccountStore) Delete(name string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := accountKey(name)
+
+	if _, ok := store.accounts[key]; !ok {
+		return ErrAccountNotFound
+	}
+
+	delete(store.accounts, key)
+	store.dirty = true
+	return nil
+}
+
+func (store *FileAccountStore) Modify(name string, update func(account *Account) error) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := accountKey(name)
+	previous, ok := store.accounts[key]
+
+	if !ok {
+		return ErrAccountNotFound
+	}
+
+	modified := previous.clone()
+
+	if err := update(modified); err != nil {
+		return err
+	}
+
+	store.accounts[key] = modified
+	store.dirty = true
+	return nil
+}
+
+func (store *FileAccountStore) Search(query AccountQuery) ([]*Account, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	results := make([]*Account, 0)
+
+	for _, account := range store.accounts {
+		if query.Matches(account) {
+			results = append(results, account.clone())
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return accountKey(results[i].Name) < accountKey(results[j].Name)
+	})
+
+	if query.Offset >= len(results) {
+		return []*Account{}, nil
+	}
+
+	results = results[query.Offset:]
+
+	if query.Limit > 0 && len(results) > query.Limit {
+		results = results[:query.Limit]
+	}
+
+	return results, nil
+}
+
+func (store *FileAccountStore) load() error {
+	data, err := os.ReadFile(store.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		// Nothing stored yet
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var file accountsFile
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		// Older stores only contained the list of accounts
+		err = json.Unmarshal(data, &file.Accounts)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	store.nextId = max(store.nextId, file.NextId)
+
+	for _, account := range file.Accounts {
+		store.accounts[accountKey(account.Name)] = account
+
+		if account.Id >= store.nextId {
+			store.nextId = account.Id + 1
+		}
+	}
+
+	// Older stores need to be rewritten with the next id
+	migrated := file.NextId == 0
+
+	for _, account := range store.accounts {
+		if account.Id == 0 {
+			// Assign ids to accounts that were created without one
+			account.Id = store.nextId
+			store.nextId++
+			migrated = true
+		}
+	}
+
+	if migrated {
+		store.dirty = true
+		return store.Flush()
+	}
+
+	return nil
+}
+
+// Write all changes to disk, if there are any. The accounts are
+// written to a temporary file first, which then replaces the old
+// file, so that a crash can never leave a half-written store behind.
+func (store *FileAccountStore) Flush() error {
+	store.flushing.Lock()
+	defer store.flushing.Unlock()
+
+	data, err := store.snapshot()
+	if err != nil || data == nil {
+		return err
+	}
+
+	temp := store.path + ".tmp"
+
+	err = os.WriteFile(temp, data, 0o600)
+
+	if err == nil {
+		err = os.Rename(temp, store.path)
+	}
+
+	if err != nil {
+		// Try again on the next flush
+		store.markDirty()
+		return err
+	}
+
+	return nil
+}
+
+// Serialize all accounts and reset the dirty flag,
+// or return nil if there are no changes to be written
+func (store *FileAccountStore) snapshot() ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if !store.dirty {
+		return nil, nil
+	}
+
+	file := accountsFile{
+		NextId:   store.nextId,
+		Accounts: make([]*Account, 0, len(store.accounts)),
+	}
+
+	for _, account := range store.accounts {
+		file.Accounts = append(file.Accounts, account)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	store.dirty = false
+	return data, nil
+}
+
+func (store *FileAccountStore) markDirty() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.dirty = true
+}
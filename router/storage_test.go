@@ -0,0 +1,154 @@
+package router
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileAccountStoreFlushAndReload(t *testing.T) {
+	directory := t.TempDir()
+
+	store, err := NewFileAccountStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := &Account{Name: "Player"}
+
+	if err := store.Create(account); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Create(&Account{Name: "player"}); err != ErrAccountExists {
+		t.Fatalf("expected ErrAccountExists, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(directory, "accounts.json")); !os.IsNotExist(err) {
+		t.Fatal("expected changes to be buffered until the next flush")
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFileAccountStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := reloaded.Get("PLAYER")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Id != account.Id || stored.Name != "Player" {
+		t.Fatalf("unexpected account after reload: %+v", stored)
+	}
+}
+
+func TestFileAccountStoreKeepsIdsOfDeletedAccounts(t *testing.T) {
+	directory := t.TempDir()
+
+	store, err := NewFileAccountStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := &Account{Name: "First"}
+	second := &Account{Name: "Second"}
+
+	if err := store.Create(first); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Create(second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Delete(second.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFileAccountStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	third := &Account{Name: "Third"}
+
+	if err := reloaded.Create(third); err != nil {
+		t.Fatal(err)
+	}
+
+	if third.Id <= second.Id {
+		t.Fatalf("expected a new id after %d, got %d", second.Id, third.Id)
+	}
+}
+
+func TestFileAccountStoreLoadAssignsMissingIds(t *testing.T) {
+	directory := t.TempDir()
+	data := `[{"Id": 4, "Name": "First"}, {"Id": 0, "Name": "Second"}]`
+
+	if err := os.WriteFile(filepath.Join(directory, "accounts.json"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileAccountStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account, err := store.Get("Second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.Id != 5 {
+		t.Fatalf("expected the migrated account to get id 5, got %d", account.Id)
+	}
+}
+
+func TestFileAccountStoreFailedFlushStaysDirty(t *testing.T) {
+	directory := t.TempDir()
+
+	store, err := NewFileAccountStore(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Create(&Account{Name: "Player"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in place of the temporary file makes the write fail
+	temp := filepath.Join(directory, "accounts.json.tmp")
+
+	if err := os.Mkdir(temp, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Flush(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+
+	if !store.dirty {
+		t.Fatal("expected the store to stay dirty after a failed flush")
+	}
+
+	if err := os.Remove(temp); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(directory, "accounts.json")); err != nil {
+		t.Fatal(err)
+	}
+}
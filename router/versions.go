@@ -0,0 +1,161 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/lekuruu/ubisoft-game-service/common"
+)
+
+const VERSION_DEFAULT_GAME = "default"
+
+var versionNumberPattern = regexp.MustCompile(`\d+`)
+
+type VersionPolicy struct {
+	Minimum string   // Oldest accepted version, empty for no minimum
+	Allowed []string // If not empty, only these versions are accepted
+}
+
+// Product ID -> version policy, where VERSION_DEFAULT_GAME is used for
+// games without an entry. Version numbers of different games can't be
+// compared, so the router login is only checked if there are no entries
+// for specific games, as the game is not known at that point.
+type VersionConfig map[string]VersionPolicy
+
+// Get the policy for the router login, or false if it should not be checked
+func (config VersionConfig) LoginPolicy() (VersionPolicy, bool) {
+	for game := range config {
+		if game != VERSION_DEFAULT_GAME {
+			return VersionPolicy{}, false
+		}
+	}
+
+	return config[VERSION_DEFAULT_GAME], true
+}
+
+func (config VersionConfig) Policy(game string) VersionPolicy {
+	if policy, ok := config[game]; ok {
+		return policy
+	}
+
+	return config[VERSION_DEFAULT_GAME]
+}
+
+// Returns an ERRORROUTER_* code, or zero if the version is accepted
+func (policy *VersionPolicy) Check(version string) int {
+	if policy.Minimum != "" && CompareVersions(version, policy.Minimum) < 0 {
+		return ERRORROUTER_CLIENTVERSIONTOOOLD
+	}
+
+	if len(policy.Allowed) == 0 {
+		return 0
+	}
+
+	for _, allowed := range policy.Allowed {
+		if allowed == version {
+			return 0
+		}
+	}
+
+	return ERRORROUTER_CLIENTINCOMPATIBLE
+}
+
+func (policy *VersionPolicy) Entry() []interface{} {
+	allowed := []interface{}{}
+
+	for _, version := range policy.Allowed {
+		allowed = append(allowed, version)
+	}
+
+	return []interface{}{policy.Minimum, allowed}
+}
+
+// Compare the numeric parts of two version strings, e.g. "1.2" < "1.10"
+func CompareVersions(a string, b string) int {
+	partsA := versionNumberPattern.FindAllString(a, -1)
+	partsB := versionNumberPattern.FindAllString(b, -1)
+
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		numberA, numberB := 0, 0
+
+		if i < len(partsA) {
+			numberA, _ = strconv.Atoi(partsA[i])
+		}
+
+		if i < len(partsB) {
+			numberB, _ = strconv.Atoi(partsB[i])
+		}
+
+		if numberA != numberB {
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// Push the version policy to all online players, after the config has changed
+func (router *Router) announceVersions() {
+	generation := router.Versions.Generation()
+
+	if generation == router.versionsGeneration {
+		return
+	}
+
+	router.versionsGeneration = generation
+	config := router.Versions.Get()
+
+	for _, player := range router.Players.All() {
+		policy := config.Policy(player.CurrentGame())
+		player.Send(NewPushMessage(GSM_UPDATEVERSIONS, policy.Entry()))
+	}
+}
+
+func handleVersionList(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	// Product ID is optional
+	game, err := common.GetStringListItem(message.Data, 0)
+
+	if err != nil || game == "" {
+		game = client.Player.CurrentGame()
+	}
+
+	policy := client.Server.Versions.Get().Policy(game)
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_VERSIONLIST), policy.Entry()}
+	return response, nil
+}
+
+func handleChangeVersion(message *common.GSMessage, client *Client) (*common.GSMessage, GSError) {
+	version, err := common.GetStringListItem(message.Data, 0)
+	if err != nil {
+		return nil, &RouterError{Message: err.Error()}
+	}
+
+	versions := client.Server.Versions.Get()
+	policy, ok := versions.Policy(client.Player.CurrentGame()), true
+
+	if client.Player.CurrentGame() == "" {
+		// Same as for the router login, without a game there is nothing to compare to
+		policy, ok = versions.LoginPolicy()
+	}
+
+	if code := policy.Check(version); ok && code != 0 {
+		return nil, &RouterError{
+			Message:      fmt.Sprintf("client version '%s' is not accepted", version),
+			ResponseCode: code,
+		}
+	}
+
+	client.Player.SetVersion(version)
+
+	response := common.NewGSMessageFromRequest(message)
+	response.Type = GSM_GSSUCCESS
+	response.Data = []interface{}{common.WriteU8(GSM_CHANGEVERSION)}
+	return response, nil
+}